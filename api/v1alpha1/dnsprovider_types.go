@@ -0,0 +1,118 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Values of DnsProviderRef.Kind
+const (
+	DnsProviderKind        = "DnsProvider"
+	ClusterDnsProviderKind = "ClusterDnsProvider"
+)
+
+// DnsProviderRef references the DnsProvider or the ClusterDnsProvider holding the credentials and the defaults of a record
+type DnsProviderRef struct {
+	// Name of the provider
+	Name string `json:"name"`
+	// Kind One of DnsProvider, in the namespace of the record, or ClusterDnsProvider. Defaults to DnsProvider
+	// +kubebuilder:validation:Enum=DnsProvider;ClusterDnsProvider
+	// +kubebuilder:default=DnsProvider
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// Route53ProviderSpec credentials and defaults of the Route53 records
+type Route53ProviderSpec struct {
+	// IAM Access Key to use to interact with AWS. Leave it empty to use the default credential chain.
+	// The secretNamespace is required in a ClusterDnsProvider
+	// +optional
+	AwsSecrets AwsSecret `json:"awsSecrets,omitempty"`
+	// RoleArn IAM role assumed with the credentials, to manage zones in another AWS account
+	// +optional
+	RoleArn string `json:"roleArn,omitempty"`
+	// ExternalId External id required by the trust policy of RoleArn
+	// +optional
+	ExternalId string `json:"externalId,omitempty"`
+	// AwsRegion AWS region used to sign the Route53 API calls, unless the record sets one.
+	// Leave it empty to use the operator default (--aws-region)
+	// +optional
+	AwsRegion string `json:"awsRegion,omitempty"`
+	// AwsEndpoint Custom Route53 endpoint URL, unless the record sets one
+	// +optional
+	AwsEndpoint string `json:"awsEndpoint,omitempty"`
+}
+
+// DnsProviderSpec defines the credentials and the defaults shared by the records referencing the provider
+type DnsProviderSpec struct {
+	// Route53 credentials of the Route53 records
+	// +optional
+	Route53 *Route53ProviderSpec `json:"route53,omitempty"`
+	// DefaultTtl ttl of the records that don't set one. Defaults to 300
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=2147483647
+	// +optional
+	DefaultTtl int64 `json:"defaultTtl,omitempty"`
+	// AllowedZones Domains the records may be created in, like example.com: the name of a record must be
+	// one of the domains or a subdomain. Leave it empty to allow any name
+	// +optional
+	AllowedZones []string `json:"allowedZones,omitempty"`
+}
+
+// DnsProvider holds the dns credentials and defaults of the DnsRecords in its namespace
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type DnsProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DnsProviderSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DnsProviderList contains a list of DnsProvider
+type DnsProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DnsProvider `json:"items"`
+}
+
+// ClusterDnsProvider holds the dns credentials and defaults of the DnsRecords in any namespace
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type ClusterDnsProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DnsProviderSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterDnsProviderList contains a list of ClusterDnsProvider
+type ClusterDnsProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterDnsProvider `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DnsProvider{}, &DnsProviderList{}, &ClusterDnsProvider{}, &ClusterDnsProviderList{})
+}
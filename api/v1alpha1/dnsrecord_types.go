@@ -24,12 +24,14 @@ import (
 RUN  make generate manifests to update the CRD manifests
 */
 
-// AwsSecret holds an AWS IAM AccessKey and SecretAccessKey
+// AwsSecret holds an AWS IAM AccessKey and SecretAccessKey.
+// Leave it empty to use the default credential chain of the operator, like IRSA or the instance role
 type AwsSecret struct {
 	// SecretName Name of the secret holding the AWS credentials
 	SecretName string `json:"secretName"`
 	// SecretNamespace The namespace containing the secret
-	// Leave it empty to use the operator namespace
+	// Leave it empty to use the namespace of the resource. A secret in another namespace must list
+	// the namespace of the resource in its net.beekube.cloud/allowed-namespaces annotation
 	SecretNamespace string `json:"secretNamespace"`
 	// AccessKeyIDKey The key that holds the AWS Access Key ID within the secret
 	AccessKeyIDKey string `json:"accessKeyIDKey"`
@@ -38,39 +40,327 @@ type AwsSecret struct {
 }
 
 type Route53Record struct {
-	// IAM Access Key to use to interact with AWS
-	AwsSecrets AwsSecret `json:"awsSecrets"`
+	// IAM Access Key to use to interact with AWS. Leave it empty to use the default credential chain
+	// +optional
+	AwsSecrets AwsSecret `json:"awsSecrets,omitempty"`
+	// RoleArn IAM role assumed with the credentials, to manage zones in another AWS account
+	// +optional
+	RoleArn string `json:"roleArn,omitempty"`
+	// ExternalId External id required by the trust policy of RoleArn
+	// +optional
+	ExternalId string `json:"externalId,omitempty"`
 	// Name Fully Qualified Domain Name
 	Name string `json:"name"`
 	// Type One of CNAME, A
 	Type string `json:"type"`
-	// ZoneId AWS Route53 ZoneID
-	ZoneId string `json:"zoneId"`
-	// ResourceRecords List of DNS target
-	ResourceRecords []string `json:"resourceRecords"`
-	// Ttl time To live in seconds
-	Ttl int64 `json:"ttl"`
+	// ZoneId AWS Route53 ZoneID. Leave it empty to use the hosted zone with the longest suffix match on Name
+	// +optional
+	ZoneId string `json:"zoneId,omitempty"`
+	// ZonePreference Which hosted zone to use when ZoneId is empty and both a public and a private zone match Name,
+	// one of Public, Private. Defaults to Public
+	// +kubebuilder:validation:Enum=Public;Private
+	// +optional
+	ZonePreference string `json:"zonePreference,omitempty"`
+	// ResourceRecords List of DNS target, in the zone file syntax.
+	// Mutually exclusive with Alias and the typed values
+	// +optional
+	ResourceRecords []string `json:"resourceRecords,omitempty"`
+	// Typed values of the MX, SRV, CAA, TXT and NAPTR records, instead of ResourceRecords
+	StructuredValues `json:",inline"`
+	// Alias Route53 alias target, like an ELB, a CloudFront distribution or an S3 website.
+	// Mutually exclusive with ResourceRecords
+	// +optional
+	Alias *Route53Alias `json:"alias,omitempty"`
+	// Ttl time To live in seconds. Defaults to the defaultTtl of the provider, or 300. Ignored for alias records
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=2147483647
+	// +optional
+	Ttl int64 `json:"ttl,omitempty"`
 	// Comment optional comment
 	Comment string `json:"comment"`
+	// Routing policy, to share the name and type with other records
+	Route53Routing `json:",inline"`
+	// AwsRegion AWS region used to sign the Route53 API calls.
+	// Leave it empty to use the operator default (--aws-region)
+	// +optional
+	AwsRegion string `json:"awsRegion,omitempty"`
+	// AwsEndpoint Custom Route53 endpoint URL, for example a local moto server. It is used for STS too when RoleArn is set.
+	// Leave it empty to use the operator default (--aws-endpoint) or the public AWS endpoint
+	// +optional
+	AwsEndpoint string `json:"awsEndpoint,omitempty"`
+}
+
+// StructuredValues typed values of a record, rendered by the dns provider in its wire format.
+// Set the field matching the type of the record
+type StructuredValues struct {
+	// MX Mail servers of a MX record
+	// +optional
+	MX []MXValue `json:"mx,omitempty"`
+	// SRV Services of a SRV record
+	// +optional
+	SRV []SRVValue `json:"srv,omitempty"`
+	// CAA Certificate authorities allowed to issue certificates, in a CAA record
+	// +optional
+	CAA []CAAValue `json:"caa,omitempty"`
+	// TXT Texts of a TXT or SPF record, without quotes: they are quoted, escaped and split in strings of 255 bytes
+	// +optional
+	TXT []string `json:"txt,omitempty"`
+	// NAPTR Rules of a NAPTR record
+	// +optional
+	NAPTR []NAPTRValue `json:"naptr,omitempty"`
+}
+
+// MXValue a mail server
+type MXValue struct {
+	// Priority The servers with the lowest priority are tried first
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Priority int32 `json:"priority"`
+	// Host Domain name of the mail server
+	Host string `json:"host"`
+}
+
+// SRVValue a server of a service
+type SRVValue struct {
+	// Priority The servers with the lowest priority are tried first
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Priority int32 `json:"priority"`
+	// Weight Share of the requests among the servers with the same priority
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Weight int32 `json:"weight"`
+	// Port Port of the service on the server
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// Target Domain name of the server
+	Target string `json:"target"`
+}
+
+// CAAValue a property of a CAA record
+type CAAValue struct {
+	// Flags 0, or 128 if the certificate authority must understand the tag
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	Flags int32 `json:"flags,omitempty"`
+	// Tag One of issue, issuewild, iodef
+	Tag string `json:"tag"`
+	// Value Like the domain of the certificate authority, or the url to report to for iodef
+	Value string `json:"value"`
+}
+
+// NAPTRValue a rule of a NAPTR record
+type NAPTRValue struct {
+	// Order The rules with the lowest order are processed first
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Order int32 `json:"order"`
+	// Preference Order of the rules with the same order
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Preference int32 `json:"preference"`
+	// Flags Like U, S, A or P
+	// +optional
+	Flags string `json:"flags,omitempty"`
+	// Service Like SIP+D2U
+	// +optional
+	Service string `json:"service,omitempty"`
+	// Regexp Substitution expression applied to the client string
+	// +optional
+	Regexp string `json:"regexp,omitempty"`
+	// Replacement Domain name to query next. Defaults to "." when Regexp is used
+	// +optional
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Values of Route53Record.ZonePreference
+const (
+	ZonePreferencePublic  = "Public"
+	ZonePreferencePrivate = "Private"
+)
+
+// Route53Alias points a record to an AWS resource. Alias records are allowed at the zone apex
+type Route53Alias struct {
+	// DNSName DNS name of the target, like the DNS name of the load balancer
+	DNSName string `json:"dnsName"`
+	// HostedZoneId Hosted zone of the target, like the canonical hosted zone of the load balancer
+	HostedZoneId string `json:"hostedZoneId"`
+	// EvaluateTargetHealth Route53 answers with the target only if it is healthy
+	// +optional
+	EvaluateTargetHealth bool `json:"evaluateTargetHealth,omitempty"`
+}
+
+// Values of Route53Routing.Failover
+const (
+	FailoverPrimary   = "PRIMARY"
+	FailoverSecondary = "SECONDARY"
+)
+
+// Route53Routing Route53 routing policy. Records with the same name and type are told apart by their SetIdentifier,
+// and must all use the same policy: one of Weight, Region, GeoLocation and Failover
+type Route53Routing struct {
+	// SetIdentifier Identifies the record among the records with the same name and type.
+	// Required with a routing policy
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+	// Weight Weighted routing: share of the answers served by this record, relative to the other records
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	Weight *int64 `json:"weight,omitempty"`
+	// Region Latency routing: AWS region of the resource the record points to
+	// +optional
+	Region string `json:"region,omitempty"`
+	// GeoLocation Geolocation routing: location of the clients served by this record
+	// +optional
+	GeoLocation *Route53GeoLocation `json:"geoLocation,omitempty"`
+	// Failover Failover routing: one of PRIMARY, SECONDARY
+	// +kubebuilder:validation:Enum=PRIMARY;SECONDARY
+	// +optional
+	Failover string `json:"failover,omitempty"`
+	// HealthCheckId Id of the Route53 health check of the record. Mutually exclusive with HealthCheckRef
+	// +optional
+	HealthCheckId string `json:"healthCheckId,omitempty"`
+	// HealthCheckRef Name of the Route53HealthCheck, in the same namespace, of the record.
+	// Mutually exclusive with HealthCheckId
+	// +optional
+	HealthCheckRef string `json:"healthCheckRef,omitempty"`
 }
 
+// Route53GeoLocation Location of the clients served by a record. Set either the continent, or the country
+// (and optionally the subdivision). Use the country "*" for the default location
+type Route53GeoLocation struct {
+	// ContinentCode Two-letter continent code, like EU
+	// +optional
+	ContinentCode string `json:"continentCode,omitempty"`
+	// CountryCode Two-letter country code, like IT
+	// +optional
+	CountryCode string `json:"countryCode,omitempty"`
+	// SubdivisionCode Subdivision of the country, like the US state code
+	// +optional
+	SubdivisionCode string `json:"subdivisionCode,omitempty"`
+}
+
+// DefaultTtl is the ttl used when Route53Record.Ttl is not set
+const DefaultTtl int64 = 300
+
+// Values of DnsRecordSpec.DriftPolicy
+const (
+	// DriftPolicyCorrect overwrite the live record when it differs from the spec
+	DriftPolicyCorrect = "Correct"
+	// DriftPolicyReport only report the drift in the Drifted condition
+	DriftPolicyReport = "Report"
+)
+
+// Values of DnsRecordSpec.AdoptPolicy
+const (
+	// AdoptPolicyNever never overwrite a record not created by the operator
+	AdoptPolicyNever = "Never"
+	// AdoptPolicyIfUnowned take over existing records that are not owned by another DnsRecord
+	AdoptPolicyIfUnowned = "IfUnowned"
+	// AdoptPolicyAlways take over existing records, even if owned by another DnsRecord or cluster
+	AdoptPolicyAlways = "Always"
+)
+
 // DnsRecordSpec defines the desired state of DnsRecord
 type DnsRecordSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	Route53Records Route53Record `json:"Route53Records"`
+	// ProviderRef The DnsProvider or ClusterDnsProvider holding the credentials and the defaults of the record.
+	// The record must not set its own credentials
+	// +optional
+	ProviderRef *DnsProviderRef `json:"providerRef,omitempty"`
+	// DriftPolicy What to do when the live record differs from the spec, one of Correct, Report.
+	// Defaults to Correct
+	// +kubebuilder:validation:Enum=Correct;Report
+	// +kubebuilder:default=Correct
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+	// AdoptPolicy What to do when the record already exists in the dns and is not owned by this DnsRecord,
+	// one of Never, IfUnowned, Always. Defaults to Never
+	// +kubebuilder:validation:Enum=Never;IfUnowned;Always
+	// +kubebuilder:default=Never
+	// +optional
+	AdoptPolicy string `json:"adoptPolicy,omitempty"`
+}
+
+// Condition types reported in DnsRecordStatus.Conditions
+const (
+	// ConditionReady the record is applied and served by the dns
+	ConditionReady = "Ready"
+	// ConditionSynced the last change to the spec has been accepted by the dns provider
+	ConditionSynced = "Synced"
+	// ConditionPropagated the dns provider reports the last change as live on its servers
+	ConditionPropagated = "Propagated"
+	// ConditionDrifted the live record differs from the spec
+	ConditionDrifted = "Drifted"
+)
+
+// AppliedRecord identifies the record last applied to the dns provider
+type AppliedRecord struct {
+	// ZoneId AWS Route53 ZoneID
+	ZoneId string `json:"zoneId"`
+	// Name Fully Qualified Domain Name
+	Name string `json:"name"`
+	// Type Record type
+	Type string `json:"type"`
+	// Ttl time To live in seconds
+	// +optional
+	Ttl int64 `json:"ttl,omitempty"`
+	// ResourceRecords List of DNS target
+	// +optional
+	ResourceRecords []string `json:"resourceRecords,omitempty"`
+	// Alias Route53 alias target
+	// +optional
+	Alias *Route53Alias `json:"alias,omitempty"`
+	// Routing policy
+	Route53Routing `json:",inline"`
 }
 
 // DnsRecordStatus defines the observed state of DnsRecord
 type DnsRecordStatus struct {
-	Status     string             `json:"status"`
-	ChangeId   string             `json:"changeId"`
-	Conditions []metav1.Condition `json:"conditions"`
+	// Status Short summary of the record state, one of Synced, Error, Deleting
+	// +optional
+	Status string `json:"status,omitempty"`
+	// ChangeId The id of the last change submitted to the dns provider
+	// +optional
+	ChangeId string `json:"changeId,omitempty"`
+	// ObservedGeneration The .metadata.generation last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastSyncTime Last time the record was applied to the dns provider
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// AppliedHash Hash of the spec last applied to the dns provider
+	// +optional
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// AppliedRecord The record last applied to the dns provider, deleted when the record is renamed or removed
+	// +optional
+	AppliedRecord *AppliedRecord `json:"appliedRecord,omitempty"`
+	// Failures Number of consecutive failed attempts to apply the record, used to compute the retry backoff
+	// +optional
+	Failures int32 `json:"failures,omitempty"`
+	// Conditions Ready, Synced, Propagated and Drifted
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // DnsRecord is the Schema for the dnsrecords API
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Name",type=string,JSONPath=`.spec.Route53Records.name`
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.Route53Records.type`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type DnsRecord struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
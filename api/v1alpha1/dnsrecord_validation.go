@@ -0,0 +1,93 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validate returns an error if the record can't be sent to Route53
+func (r *Route53Record) Validate() error {
+	if err := ValidateName(r.Name); err != nil {
+		return err
+	}
+	if !isRecordType(r.Type) {
+		return fmt.Errorf("invalid type %q: must be one of %s", r.Type, strings.Join(RecordTypes, ", "))
+	}
+	sources := 0
+	for _, set := range []bool{r.Alias != nil, len(r.ResourceRecords) > 0, !r.StructuredValues.IsEmpty()} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return errors.New("exactly one of alias, resourceRecords or mx, srv, caa, txt, naptr is required")
+	}
+	if r.Alias != nil && (r.Alias.DNSName == "" || r.Alias.HostedZoneId == "") {
+		return errors.New("alias requires dnsName and hostedZoneId")
+	}
+	if len(r.ResourceRecords) > 0 {
+		if err := ValidateValues(r.Type, r.ResourceRecords); err != nil {
+			return err
+		}
+	}
+	if err := r.StructuredValues.Validate(r.Type); err != nil {
+		return err
+	}
+	if err := r.Route53Routing.Validate(); err != nil {
+		return err
+	}
+	if r.Ttl < 0 {
+		return fmt.Errorf("invalid ttl %d: must be a positive number of seconds", r.Ttl)
+	}
+	return nil
+}
+
+func isRecordType(recordType string) bool {
+	for _, t := range RecordTypes {
+		if t == recordType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error if the routing policy is incomplete or mixes several policies
+func (r *Route53Routing) Validate() error {
+	policies := 0
+	for _, set := range []bool{r.Weight != nil, r.Region != "", r.GeoLocation != nil, r.Failover != ""} {
+		if set {
+			policies++
+		}
+	}
+
+	switch {
+	case policies > 1:
+		return errors.New("weight, region, geoLocation and failover are mutually exclusive")
+	case policies == 1 && r.SetIdentifier == "":
+		return errors.New("setIdentifier is required with a routing policy")
+	case policies == 0 && r.SetIdentifier != "":
+		return errors.New("setIdentifier requires one of weight, region, geoLocation or failover")
+	case r.HealthCheckId != "" && r.HealthCheckRef != "":
+		return errors.New("healthCheckId and healthCheckRef are mutually exclusive")
+	case r.Failover != "" && r.Failover != FailoverPrimary && r.Failover != FailoverSecondary:
+		return fmt.Errorf("invalid failover %s: must be one of %s, %s", r.Failover, FailoverPrimary, FailoverSecondary)
+	}
+	return nil
+}
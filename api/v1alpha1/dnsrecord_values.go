@@ -0,0 +1,200 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RecordTypes the record types supported by Route53
+var RecordTypes = []string{"A", "AAAA", "CAA", "CNAME", "DS", "MX", "NAPTR", "NS", "PTR", "SOA", "SPF", "SRV", "TXT"}
+
+// dnsLabel a label of a domain name. Underscores are allowed for the service labels, like _sip._tcp
+var dnsLabel = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9_])?$`)
+
+// caaTag the property tag of a CAA record, like issue or iodef
+var caaTag = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// ValidateName returns an error if name is not a valid domain name. The first label may be the wildcard *
+func ValidateName(name string) error {
+	fqdn := strings.TrimSuffix(name, ".")
+	if fqdn == "" || len(fqdn) > 253 {
+		return fmt.Errorf("invalid name %q: must be a domain name of at most 253 characters", name)
+	}
+	for i, label := range strings.Split(fqdn, ".") {
+		if i == 0 && label == "*" {
+			continue
+		}
+		if !dnsLabel.MatchString(label) {
+			return fmt.Errorf("invalid name %q: %q is not a valid label", name, label)
+		}
+	}
+	return nil
+}
+
+// ValidateValues returns an error if the values are not valid for the record type
+func ValidateValues(recordType string, values []string) error {
+	if recordType == "CNAME" && len(values) != 1 {
+		return fmt.Errorf("a CNAME record must have exactly one value, got %d", len(values))
+	}
+	for _, value := range values {
+		if err := validateValue(recordType, value); err != nil {
+			return fmt.Errorf("invalid %s value %q: %w", recordType, value, err)
+		}
+	}
+	return nil
+}
+
+func validateValue(recordType, value string) error {
+	fields := strings.Fields(value)
+	switch recordType {
+	case "A":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil || strings.Contains(value, ":") {
+			return errors.New("must be an IPv4 address")
+		}
+	case "AAAA":
+		if ip := net.ParseIP(value); ip == nil || !strings.Contains(value, ":") {
+			return errors.New("must be an IPv6 address")
+		}
+	case "CNAME", "NS", "PTR":
+		return ValidateName(value)
+	case "MX":
+		if len(fields) != 2 {
+			return errors.New("must be <priority> <host>")
+		}
+		if err := validateUint(fields[0], "priority", 65535); err != nil {
+			return err
+		}
+		return ValidateName(fields[1])
+	case "SRV":
+		if len(fields) != 4 {
+			return errors.New("must be <priority> <weight> <port> <target>")
+		}
+		for i, field := range []string{"priority", "weight", "port"} {
+			if err := validateUint(fields[i], field, 65535); err != nil {
+				return err
+			}
+		}
+		return ValidateName(fields[3])
+	case "CAA":
+		if len(fields) < 3 {
+			return errors.New(`must be <flags> <tag> "<value>"`)
+		}
+		if err := validateUint(fields[0], "flags", 255); err != nil {
+			return err
+		}
+		if !caaTag.MatchString(fields[1]) {
+			return fmt.Errorf("invalid tag %q", fields[1])
+		}
+	}
+	return nil
+}
+
+// IsEmpty true if none of the typed values is set
+func (v StructuredValues) IsEmpty() bool {
+	return len(v.MX) == 0 && len(v.SRV) == 0 && len(v.CAA) == 0 && len(v.TXT) == 0 && len(v.NAPTR) == 0
+}
+
+// Validate returns an error if the typed values don't match the record type or are invalid
+func (v StructuredValues) Validate(recordType string) error {
+	if v.IsEmpty() {
+		return nil
+	}
+	expected := recordType
+	if recordType == "SPF" {
+		expected = "TXT"
+	}
+	fields := []struct {
+		recordType string
+		set        bool
+	}{
+		{"MX", len(v.MX) > 0},
+		{"SRV", len(v.SRV) > 0},
+		{"CAA", len(v.CAA) > 0},
+		{"TXT", len(v.TXT) > 0},
+		{"NAPTR", len(v.NAPTR) > 0},
+	}
+	for _, field := range fields {
+		if field.set && field.recordType != expected {
+			return fmt.Errorf("%s can't be set in a %s record", strings.ToLower(field.recordType), recordType)
+		}
+	}
+
+	for _, mx := range v.MX {
+		if err := validateRange(mx.Priority, "priority", 65535); err != nil {
+			return fmt.Errorf("invalid mx: %w", err)
+		}
+		if err := ValidateName(mx.Host); err != nil {
+			return fmt.Errorf("invalid mx host: %w", err)
+		}
+	}
+	for _, srv := range v.SRV {
+		for i, n := range []int32{srv.Priority, srv.Weight, srv.Port} {
+			if err := validateRange(n, []string{"priority", "weight", "port"}[i], 65535); err != nil {
+				return fmt.Errorf("invalid srv: %w", err)
+			}
+		}
+		if err := ValidateName(srv.Target); err != nil {
+			return fmt.Errorf("invalid srv target: %w", err)
+		}
+	}
+	for _, caa := range v.CAA {
+		if err := validateRange(caa.Flags, "flags", 255); err != nil {
+			return fmt.Errorf("invalid caa: %w", err)
+		}
+		if !caaTag.MatchString(caa.Tag) {
+			return fmt.Errorf("invalid caa tag %q", caa.Tag)
+		}
+	}
+	for _, naptr := range v.NAPTR {
+		if err := validateRange(naptr.Order, "order", 65535); err != nil {
+			return fmt.Errorf("invalid naptr: %w", err)
+		}
+		if err := validateRange(naptr.Preference, "preference", 65535); err != nil {
+			return fmt.Errorf("invalid naptr: %w", err)
+		}
+		if naptr.Replacement != "" && naptr.Replacement != "." {
+			if err := ValidateName(naptr.Replacement); err != nil {
+				return fmt.Errorf("invalid naptr replacement: %w", err)
+			}
+		}
+		if naptr.Regexp != "" && naptr.Replacement != "" && naptr.Replacement != "." {
+			return errors.New("invalid naptr: regexp and replacement are mutually exclusive")
+		}
+	}
+	return nil
+}
+
+func validateRange(value int32, field string, max int32) error {
+	if value < 0 || value > max {
+		return fmt.Errorf("%s %d must be a number between 0 and %d", field, value, max)
+	}
+	return nil
+}
+
+func validateUint(value, field string, max uint64) error {
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || n > max {
+		return fmt.Errorf("%s %q must be a number between 0 and %d", field, value, max)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Values of Route53HealthCheckSpec.Type
+const (
+	HealthCheckHttp       = "HTTP"
+	HealthCheckHttps      = "HTTPS"
+	HealthCheckTcp        = "TCP"
+	HealthCheckCalculated = "CALCULATED"
+)
+
+// Route53HealthCheckSpec defines the desired state of Route53HealthCheck
+type Route53HealthCheckSpec struct {
+	// IAM Access Key to use to interact with AWS. Leave it empty to use the default credential chain
+	// +optional
+	AwsSecrets AwsSecret `json:"awsSecrets,omitempty"`
+	// RoleArn IAM role assumed with the credentials, to manage health checks in another AWS account
+	// +optional
+	RoleArn string `json:"roleArn,omitempty"`
+	// ExternalId External id required by the trust policy of RoleArn
+	// +optional
+	ExternalId string `json:"externalId,omitempty"`
+	// AwsRegion AWS region used to sign the Route53 API calls.
+	// Leave it empty to use the operator default (--aws-region)
+	// +optional
+	AwsRegion string `json:"awsRegion,omitempty"`
+	// AwsEndpoint Custom Route53 endpoint URL.
+	// Leave it empty to use the operator default (--aws-endpoint) or the public AWS endpoint
+	// +optional
+	AwsEndpoint string `json:"awsEndpoint,omitempty"`
+
+	// Type One of HTTP, HTTPS, TCP, CALCULATED. It can't be changed once the health check is created
+	// +kubebuilder:validation:Enum=HTTP;HTTPS;TCP;CALCULATED
+	Type string `json:"type"`
+	// Fqdn Domain name of the endpoint to check. For HTTP and HTTPS checks it is also sent as Host header
+	// +optional
+	Fqdn string `json:"fqdn,omitempty"`
+	// IPAddress IP address of the endpoint to check. Leave it empty to resolve Fqdn
+	// +optional
+	IPAddress string `json:"ipAddress,omitempty"`
+	// Port Port of the endpoint. Defaults to 80 for HTTP and 443 for HTTPS
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// Path Path requested by HTTP and HTTPS checks, like /healthz
+	// +optional
+	Path string `json:"path,omitempty"`
+	// RequestInterval Seconds between two checks, one of 10, 30. It can't be changed once the health check is created
+	// +kubebuilder:validation:Enum=10;30
+	// +kubebuilder:default=30
+	// +optional
+	RequestInterval int32 `json:"requestInterval,omitempty"`
+	// FailureThreshold Number of consecutive checks that must fail (or succeed) to change the endpoint status
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=3
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+	// Regions AWS regions the checks are run from. Leave it empty to use the Route53 defaults
+	// +optional
+	Regions []string `json:"regions,omitempty"`
+	// ChildHealthChecks CALCULATED checks only: names of the Route53HealthChecks, in the same namespace, to combine
+	// +optional
+	ChildHealthChecks []string `json:"childHealthChecks,omitempty"`
+	// HealthThreshold CALCULATED checks only: number of children that must be healthy
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=256
+	// +optional
+	HealthThreshold int32 `json:"healthThreshold,omitempty"`
+}
+
+// Route53HealthCheckStatus defines the observed state of Route53HealthCheck
+type Route53HealthCheckStatus struct {
+	// HealthCheckId The id of the Route53 health check
+	// +optional
+	HealthCheckId string `json:"healthCheckId,omitempty"`
+	// ObservedGeneration The .metadata.generation last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions Ready
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Route53HealthCheck is the Schema for the route53healthchecks API
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="Id",type=string,JSONPath=`.status.healthCheckId`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type Route53HealthCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   Route53HealthCheckSpec   `json:"spec,omitempty"`
+	Status Route53HealthCheckStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// Route53HealthCheckList contains a list of Route53HealthCheck
+type Route53HealthCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Route53HealthCheck `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Route53HealthCheck{}, &Route53HealthCheckList{})
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+)
+
+// Validate returns an error if the health check can't be sent to Route53
+func (s *Route53HealthCheckSpec) Validate() error {
+	switch s.Type {
+	case HealthCheckCalculated:
+		if len(s.ChildHealthChecks) == 0 {
+			return errors.New("CALCULATED health checks require childHealthChecks")
+		}
+		if s.Fqdn != "" || s.IPAddress != "" || s.Path != "" {
+			return errors.New("CALCULATED health checks don't check an endpoint: remove fqdn, ipAddress and path")
+		}
+	case HealthCheckHttp, HealthCheckHttps, HealthCheckTcp:
+		if s.Fqdn == "" && s.IPAddress == "" {
+			return errors.New("one of fqdn or ipAddress is required")
+		}
+		if s.Type == HealthCheckTcp && s.Port == 0 {
+			return errors.New("TCP health checks require a port")
+		}
+		if len(s.ChildHealthChecks) > 0 {
+			return errors.New("childHealthChecks is allowed only for CALCULATED health checks")
+		}
+	default:
+		return errors.New("type must be one of HTTP, HTTPS, TCP, CALCULATED")
+	}
+	return nil
+}
@@ -26,6 +26,32 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedRecord) DeepCopyInto(out *AppliedRecord) {
+	*out = *in
+	if in.ResourceRecords != nil {
+		in, out := &in.ResourceRecords, &out.ResourceRecords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Alias != nil {
+		in, out := &in.Alias, &out.Alias
+		*out = new(Route53Alias)
+		**out = **in
+	}
+	in.Route53Routing.DeepCopyInto(&out.Route53Routing)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedRecord.
+func (in *AppliedRecord) DeepCopy() *AppliedRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AwsSecret) DeepCopyInto(out *AwsSecret) {
 	*out = *in
@@ -41,6 +67,177 @@ func (in *AwsSecret) DeepCopy() *AwsSecret {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CAAValue) DeepCopyInto(out *CAAValue) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CAAValue.
+func (in *CAAValue) DeepCopy() *CAAValue {
+	if in == nil {
+		return nil
+	}
+	out := new(CAAValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDnsProvider) DeepCopyInto(out *ClusterDnsProvider) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDnsProvider.
+func (in *ClusterDnsProvider) DeepCopy() *ClusterDnsProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDnsProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterDnsProvider) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDnsProviderList) DeepCopyInto(out *ClusterDnsProviderList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterDnsProvider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDnsProviderList.
+func (in *ClusterDnsProviderList) DeepCopy() *ClusterDnsProviderList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDnsProviderList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterDnsProviderList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DnsProvider) DeepCopyInto(out *DnsProvider) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DnsProvider.
+func (in *DnsProvider) DeepCopy() *DnsProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(DnsProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DnsProvider) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DnsProviderList) DeepCopyInto(out *DnsProviderList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DnsProvider, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DnsProviderList.
+func (in *DnsProviderList) DeepCopy() *DnsProviderList {
+	if in == nil {
+		return nil
+	}
+	out := new(DnsProviderList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DnsProviderList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DnsProviderRef) DeepCopyInto(out *DnsProviderRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DnsProviderRef.
+func (in *DnsProviderRef) DeepCopy() *DnsProviderRef {
+	if in == nil {
+		return nil
+	}
+	out := new(DnsProviderRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DnsProviderSpec) DeepCopyInto(out *DnsProviderSpec) {
+	*out = *in
+	if in.Route53 != nil {
+		in, out := &in.Route53, &out.Route53
+		*out = new(Route53ProviderSpec)
+		**out = **in
+	}
+	if in.AllowedZones != nil {
+		in, out := &in.AllowedZones, &out.AllowedZones
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DnsProviderSpec.
+func (in *DnsProviderSpec) DeepCopy() *DnsProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DnsProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DnsRecord) DeepCopyInto(out *DnsRecord) {
 	*out = *in
@@ -104,6 +301,11 @@ func (in *DnsRecordList) DeepCopyObject() runtime.Object {
 func (in *DnsRecordSpec) DeepCopyInto(out *DnsRecordSpec) {
 	*out = *in
 	in.Route53Records.DeepCopyInto(&out.Route53Records)
+	if in.ProviderRef != nil {
+		in, out := &in.ProviderRef, &out.ProviderRef
+		*out = new(DnsProviderRef)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DnsRecordSpec.
@@ -119,6 +321,15 @@ func (in *DnsRecordSpec) DeepCopy() *DnsRecordSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DnsRecordStatus) DeepCopyInto(out *DnsRecordStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.AppliedRecord != nil {
+		in, out := &in.AppliedRecord, &out.AppliedRecord
+		*out = new(AppliedRecord)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -138,6 +349,189 @@ func (in *DnsRecordStatus) DeepCopy() *DnsRecordStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MXValue) DeepCopyInto(out *MXValue) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MXValue.
+func (in *MXValue) DeepCopy() *MXValue {
+	if in == nil {
+		return nil
+	}
+	out := new(MXValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NAPTRValue) DeepCopyInto(out *NAPTRValue) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NAPTRValue.
+func (in *NAPTRValue) DeepCopy() *NAPTRValue {
+	if in == nil {
+		return nil
+	}
+	out := new(NAPTRValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53Alias) DeepCopyInto(out *Route53Alias) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53Alias.
+func (in *Route53Alias) DeepCopy() *Route53Alias {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53Alias)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53GeoLocation) DeepCopyInto(out *Route53GeoLocation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53GeoLocation.
+func (in *Route53GeoLocation) DeepCopy() *Route53GeoLocation {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53GeoLocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53HealthCheck) DeepCopyInto(out *Route53HealthCheck) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53HealthCheck.
+func (in *Route53HealthCheck) DeepCopy() *Route53HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Route53HealthCheck) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53HealthCheckList) DeepCopyInto(out *Route53HealthCheckList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Route53HealthCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53HealthCheckList.
+func (in *Route53HealthCheckList) DeepCopy() *Route53HealthCheckList {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53HealthCheckList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Route53HealthCheckList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53HealthCheckSpec) DeepCopyInto(out *Route53HealthCheckSpec) {
+	*out = *in
+	out.AwsSecrets = in.AwsSecrets
+	if in.Regions != nil {
+		in, out := &in.Regions, &out.Regions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ChildHealthChecks != nil {
+		in, out := &in.ChildHealthChecks, &out.ChildHealthChecks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53HealthCheckSpec.
+func (in *Route53HealthCheckSpec) DeepCopy() *Route53HealthCheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53HealthCheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53HealthCheckStatus) DeepCopyInto(out *Route53HealthCheckStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53HealthCheckStatus.
+func (in *Route53HealthCheckStatus) DeepCopy() *Route53HealthCheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53HealthCheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53ProviderSpec) DeepCopyInto(out *Route53ProviderSpec) {
+	*out = *in
+	out.AwsSecrets = in.AwsSecrets
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53ProviderSpec.
+func (in *Route53ProviderSpec) DeepCopy() *Route53ProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53ProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Route53Record) DeepCopyInto(out *Route53Record) {
 	*out = *in
@@ -147,6 +541,13 @@ func (in *Route53Record) DeepCopyInto(out *Route53Record) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	in.StructuredValues.DeepCopyInto(&out.StructuredValues)
+	if in.Alias != nil {
+		in, out := &in.Alias, &out.Alias
+		*out = new(Route53Alias)
+		**out = **in
+	}
+	in.Route53Routing.DeepCopyInto(&out.Route53Routing)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53Record.
@@ -158,3 +559,83 @@ func (in *Route53Record) DeepCopy() *Route53Record {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route53Routing) DeepCopyInto(out *Route53Routing) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int64)
+		**out = **in
+	}
+	if in.GeoLocation != nil {
+		in, out := &in.GeoLocation, &out.GeoLocation
+		*out = new(Route53GeoLocation)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route53Routing.
+func (in *Route53Routing) DeepCopy() *Route53Routing {
+	if in == nil {
+		return nil
+	}
+	out := new(Route53Routing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SRVValue) DeepCopyInto(out *SRVValue) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SRVValue.
+func (in *SRVValue) DeepCopy() *SRVValue {
+	if in == nil {
+		return nil
+	}
+	out := new(SRVValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructuredValues) DeepCopyInto(out *StructuredValues) {
+	*out = *in
+	if in.MX != nil {
+		in, out := &in.MX, &out.MX
+		*out = make([]MXValue, len(*in))
+		copy(*out, *in)
+	}
+	if in.SRV != nil {
+		in, out := &in.SRV, &out.SRV
+		*out = make([]SRVValue, len(*in))
+		copy(*out, *in)
+	}
+	if in.CAA != nil {
+		in, out := &in.CAA, &out.CAA
+		*out = make([]CAAValue, len(*in))
+		copy(*out, *in)
+	}
+	if in.TXT != nil {
+		in, out := &in.TXT, &out.TXT
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NAPTR != nil {
+		in, out := &in.NAPTR, &out.NAPTR
+		*out = make([]NAPTRValue, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructuredValues.
+func (in *StructuredValues) DeepCopy() *StructuredValues {
+	if in == nil {
+		return nil
+	}
+	out := new(StructuredValues)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts this DnsRecord to the Hub version (v1alpha1).
+// The reconciler applies the single record of v1alpha1: a DnsRecord with several records is rejected,
+// instead of storing records that would never be applied
+func (src *DnsRecord) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.DnsRecord)
+	if len(src.Spec.Records) > 1 {
+		return fmt.Errorf("the DnsRecord %s/%s has %d records: a single record per DnsRecord is supported, create a DnsRecord for each record",
+			src.Namespace, src.Name, len(src.Spec.Records))
+	}
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec.DriftPolicy = src.Spec.DriftPolicy
+	dst.Spec.AdoptPolicy = src.Spec.AdoptPolicy
+	if ref := src.Spec.ProviderRef; ref != nil {
+		dst.Spec.ProviderRef = &v1alpha1.DnsProviderRef{Name: ref.Name, Kind: ref.Kind}
+	}
+
+	route53 := &dst.Spec.Route53Records
+	if credentials := src.Spec.Route53; credentials != nil {
+		if credentials.AwsSecrets != nil {
+			route53.AwsSecrets = v1alpha1.AwsSecret(*credentials.AwsSecrets)
+		}
+		route53.RoleArn = credentials.RoleArn
+		route53.ExternalId = credentials.ExternalId
+		route53.AwsRegion = credentials.AwsRegion
+		route53.AwsEndpoint = credentials.AwsEndpoint
+	}
+	if len(src.Spec.Records) > 0 {
+		record := src.Spec.Records[0]
+		route53.Name = record.Name
+		route53.Type = record.Type
+		route53.Ttl = record.Ttl
+		route53.ResourceRecords = record.Values
+		route53.StructuredValues = structuredToHub(record.StructuredValues)
+		route53.Comment = record.Comment
+		if options := record.Route53; options != nil {
+			route53.ZoneId = options.ZoneId
+			route53.ZonePreference = options.ZonePreference
+			route53.Alias = aliasToHub(options.Alias)
+			route53.Route53Routing = routingToHub(options.Route53Routing)
+		}
+	}
+
+	dst.Status = v1alpha1.DnsRecordStatus{
+		Status:             src.Status.Status,
+		ChangeId:           src.Status.ChangeId,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		LastSyncTime:       src.Status.LastSyncTime,
+		AppliedHash:        src.Status.AppliedHash,
+		Failures:           src.Status.Failures,
+		Conditions:         src.Status.Conditions,
+	}
+	if applied := src.Status.AppliedRecord; applied != nil {
+		dst.Status.AppliedRecord = &v1alpha1.AppliedRecord{
+			ZoneId:          applied.ZoneId,
+			Name:            applied.Name,
+			Type:            applied.Type,
+			Ttl:             applied.Ttl,
+			ResourceRecords: applied.ResourceRecords,
+			Alias:           aliasToHub(applied.Alias),
+			Route53Routing:  routingToHub(applied.Route53Routing),
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this version
+func (dst *DnsRecord) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.DnsRecord)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec.DriftPolicy = src.Spec.DriftPolicy
+	dst.Spec.AdoptPolicy = src.Spec.AdoptPolicy
+	if ref := src.Spec.ProviderRef; ref != nil {
+		dst.Spec.ProviderRef = &DnsProviderRef{Name: ref.Name, Kind: ref.Kind}
+	}
+
+	route53 := src.Spec.Route53Records
+	credentials := Route53Credentials{
+		RoleArn:     route53.RoleArn,
+		ExternalId:  route53.ExternalId,
+		AwsRegion:   route53.AwsRegion,
+		AwsEndpoint: route53.AwsEndpoint,
+	}
+	if route53.AwsSecrets != (v1alpha1.AwsSecret{}) {
+		secrets := AwsSecret(route53.AwsSecrets)
+		credentials.AwsSecrets = &secrets
+	}
+	if credentials != (Route53Credentials{}) {
+		dst.Spec.Route53 = &credentials
+	}
+
+	record := Record{
+		Name:             route53.Name,
+		Type:             route53.Type,
+		Ttl:              route53.Ttl,
+		Values:           route53.ResourceRecords,
+		StructuredValues: structuredFromHub(route53.StructuredValues),
+		Comment:          route53.Comment,
+	}
+	options := Route53RecordOptions{
+		ZoneId:         route53.ZoneId,
+		ZonePreference: route53.ZonePreference,
+		Alias:          aliasFromHub(route53.Alias),
+		Route53Routing: routingFromHub(route53.Route53Routing),
+	}
+	if options.ZoneId != "" || options.ZonePreference != "" || options.Alias != nil || options.Route53Routing != (Route53Routing{}) {
+		record.Route53 = &options
+	}
+	dst.Spec.Records = []Record{record}
+
+	dst.Status = DnsRecordStatus{
+		Status:             src.Status.Status,
+		ChangeId:           src.Status.ChangeId,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		LastSyncTime:       src.Status.LastSyncTime,
+		AppliedHash:        src.Status.AppliedHash,
+		Failures:           src.Status.Failures,
+		Conditions:         src.Status.Conditions,
+	}
+	if applied := src.Status.AppliedRecord; applied != nil {
+		dst.Status.AppliedRecord = &AppliedRecord{
+			ZoneId:          applied.ZoneId,
+			Name:            applied.Name,
+			Type:            applied.Type,
+			Ttl:             applied.Ttl,
+			ResourceRecords: applied.ResourceRecords,
+			Alias:           aliasFromHub(applied.Alias),
+			Route53Routing:  routingFromHub(applied.Route53Routing),
+		}
+	}
+	return nil
+}
+
+func structuredToHub(values StructuredValues) v1alpha1.StructuredValues {
+	var converted v1alpha1.StructuredValues
+	for _, mx := range values.MX {
+		converted.MX = append(converted.MX, v1alpha1.MXValue(mx))
+	}
+	for _, srv := range values.SRV {
+		converted.SRV = append(converted.SRV, v1alpha1.SRVValue(srv))
+	}
+	for _, caa := range values.CAA {
+		converted.CAA = append(converted.CAA, v1alpha1.CAAValue(caa))
+	}
+	converted.TXT = values.TXT
+	for _, naptr := range values.NAPTR {
+		converted.NAPTR = append(converted.NAPTR, v1alpha1.NAPTRValue(naptr))
+	}
+	return converted
+}
+
+func structuredFromHub(values v1alpha1.StructuredValues) StructuredValues {
+	var converted StructuredValues
+	for _, mx := range values.MX {
+		converted.MX = append(converted.MX, MXValue(mx))
+	}
+	for _, srv := range values.SRV {
+		converted.SRV = append(converted.SRV, SRVValue(srv))
+	}
+	for _, caa := range values.CAA {
+		converted.CAA = append(converted.CAA, CAAValue(caa))
+	}
+	converted.TXT = values.TXT
+	for _, naptr := range values.NAPTR {
+		converted.NAPTR = append(converted.NAPTR, NAPTRValue(naptr))
+	}
+	return converted
+}
+
+func aliasToHub(alias *Route53Alias) *v1alpha1.Route53Alias {
+	if alias == nil {
+		return nil
+	}
+	converted := v1alpha1.Route53Alias(*alias)
+	return &converted
+}
+
+func aliasFromHub(alias *v1alpha1.Route53Alias) *Route53Alias {
+	if alias == nil {
+		return nil
+	}
+	converted := Route53Alias(*alias)
+	return &converted
+}
+
+func routingToHub(routing Route53Routing) v1alpha1.Route53Routing {
+	converted := v1alpha1.Route53Routing{
+		SetIdentifier:  routing.SetIdentifier,
+		Weight:         routing.Weight,
+		Region:         routing.Region,
+		Failover:       routing.Failover,
+		HealthCheckId:  routing.HealthCheckId,
+		HealthCheckRef: routing.HealthCheckRef,
+	}
+	if routing.GeoLocation != nil {
+		location := v1alpha1.Route53GeoLocation(*routing.GeoLocation)
+		converted.GeoLocation = &location
+	}
+	return converted
+}
+
+func routingFromHub(routing v1alpha1.Route53Routing) Route53Routing {
+	converted := Route53Routing{
+		SetIdentifier:  routing.SetIdentifier,
+		Weight:         routing.Weight,
+		Region:         routing.Region,
+		Failover:       routing.Failover,
+		HealthCheckId:  routing.HealthCheckId,
+		HealthCheckRef: routing.HealthCheckRef,
+	}
+	if routing.GeoLocation != nil {
+		location := Route53GeoLocation(*routing.GeoLocation)
+		converted.GeoLocation = &location
+	}
+	return converted
+}
@@ -0,0 +1,118 @@
+package v1beta1
+
+import (
+	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+)
+
+func TestDnsRecordRoundTripsFromHub(t *testing.T) {
+	weight := int64(10)
+	hub := &v1alpha1.DnsRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: "www", Namespace: "default", Generation: 2},
+		Spec: v1alpha1.DnsRecordSpec{
+			Route53Records: v1alpha1.Route53Record{
+				AwsSecrets:      v1alpha1.AwsSecret{SecretName: "aws", SecretNamespace: "default", AccessKeyIDKey: "id", SecretAccessKeyKey: "secret"},
+				RoleArn:         "arn:aws:iam::123456789012:role/dns",
+				Name:            "www.example.com.",
+				Type:            "A",
+				ZoneId:          "Z123",
+				ResourceRecords: []string{"10.0.0.1"},
+				Ttl:             60,
+				Comment:         "default/www",
+				Route53Routing: v1alpha1.Route53Routing{
+					SetIdentifier: "eu",
+					Weight:        &weight,
+				},
+				AwsRegion: "eu-west-1",
+			},
+			DriftPolicy: v1alpha1.DriftPolicyReport,
+			AdoptPolicy: v1alpha1.AdoptPolicyNever,
+		},
+		Status: v1alpha1.DnsRecordStatus{
+			Status:        "Synced",
+			AppliedRecord: &v1alpha1.AppliedRecord{ZoneId: "Z123", Name: "www.example.com.", Type: "A", Ttl: 60, ResourceRecords: []string{"10.0.0.1"}},
+		},
+	}
+
+	for name, change := range map[string]func(*v1alpha1.DnsRecord){
+		"route53": func(*v1alpha1.DnsRecord) {},
+		"alias": func(hub *v1alpha1.DnsRecord) {
+			hub.Spec.Route53Records.ResourceRecords = nil
+			hub.Spec.Route53Records.Alias = &v1alpha1.Route53Alias{DNSName: "lb.amazonaws.com", HostedZoneId: "ZLB", EvaluateTargetHealth: true}
+			hub.Spec.Route53Records.Route53Routing = v1alpha1.Route53Routing{
+				SetIdentifier: "it",
+				GeoLocation:   &v1alpha1.Route53GeoLocation{CountryCode: "IT"},
+			}
+		},
+		"typed values": func(hub *v1alpha1.DnsRecord) {
+			hub.Spec.Route53Records.Type = "SRV"
+			hub.Spec.Route53Records.ResourceRecords = nil
+			hub.Spec.Route53Records.SRV = []v1alpha1.SRVValue{{Priority: 1, Weight: 10, Port: 5060, Target: "sip.example.com"}}
+		},
+		"provider": func(hub *v1alpha1.DnsRecord) {
+			hub.Spec.Route53Records.AwsSecrets = v1alpha1.AwsSecret{}
+			hub.Spec.Route53Records.RoleArn = ""
+			hub.Spec.Route53Records.AwsRegion = ""
+			hub.Spec.ProviderRef = &v1alpha1.DnsProviderRef{Name: "route53", Kind: v1alpha1.ClusterDnsProviderKind}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			original := hub.DeepCopy()
+			change(original)
+
+			spoke := &DnsRecord{}
+			if err := spoke.ConvertFrom(original); err != nil {
+				t.Fatal(err)
+			}
+			converted := &v1alpha1.DnsRecord{}
+			if err := spoke.ConvertTo(converted); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(original, converted) {
+				t.Errorf("round trip changed the record:\n%+v\n%+v", original, converted)
+			}
+		})
+	}
+}
+
+func TestDnsRecordRoundTripsToHub(t *testing.T) {
+	spoke := &DnsRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: "www", Namespace: "default"},
+		Spec: DnsRecordSpec{
+			ProviderRef: &DnsProviderRef{Name: "route53", Kind: "DnsProvider"},
+			Records: []Record{{
+				Name:    "www.example.com.",
+				Type:    "CNAME",
+				Values:  []string{"lb.example.com"},
+				Route53: &Route53RecordOptions{ZonePreference: "Private"},
+			}},
+		},
+	}
+
+	hub := &v1alpha1.DnsRecord{}
+	if err := spoke.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	if hub.Spec.Route53Records.Name != "www.example.com." || hub.Spec.Route53Records.ZonePreference != "Private" || hub.Spec.ProviderRef.Name != "route53" {
+		t.Errorf("unexpected hub record: %+v", hub.Spec)
+	}
+	converted := &DnsRecord{}
+	if err := converted.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(spoke, converted) {
+		t.Errorf("round trip changed the record:\n%+v\n%+v", spoke, converted)
+	}
+}
+
+func TestDnsRecordRejectsSeveralRecords(t *testing.T) {
+	spoke := &DnsRecord{Spec: DnsRecordSpec{Records: []Record{
+		{Name: "www.example.com.", Type: "A", Values: []string{"10.0.0.1"}},
+		{Name: "www.example.com.", Type: "AAAA", Values: []string{"fd00::1"}},
+	}}}
+	if err := spoke.ConvertTo(&v1alpha1.DnsRecord{}); err == nil {
+		t.Error("several records must be rejected, they would not be applied")
+	}
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AwsSecret holds an AWS IAM AccessKey and SecretAccessKey
+type AwsSecret struct {
+	// SecretName Name of the secret holding the AWS credentials
+	SecretName string `json:"secretName"`
+	// SecretNamespace The namespace containing the secret. Leave it empty to use the namespace of the resource.
+	// A secret in another namespace must list the namespace of the resource in its
+	// net.beekube.cloud/allowed-namespaces annotation
+	// +optional
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+	// AccessKeyIDKey The key that holds the AWS Access Key ID within the secret
+	AccessKeyIDKey string `json:"accessKeyIDKey"`
+	// SecretAccessKeyKey The key that holds the AWS Secret Access Key within the secret
+	SecretAccessKeyKey string `json:"secretAccessKeyKey"`
+}
+
+// Route53Credentials credentials and endpoint of the Route53 API
+type Route53Credentials struct {
+	// AwsSecrets IAM Access Key to use to interact with AWS. Leave it empty to use the default credential chain
+	// +optional
+	AwsSecrets *AwsSecret `json:"awsSecrets,omitempty"`
+	// RoleArn IAM role assumed with the credentials, to manage zones in another AWS account
+	// +optional
+	RoleArn string `json:"roleArn,omitempty"`
+	// ExternalId External id required by the trust policy of RoleArn
+	// +optional
+	ExternalId string `json:"externalId,omitempty"`
+	// AwsRegion AWS region used to sign the Route53 API calls.
+	// Leave it empty to use the operator default (--aws-region)
+	// +optional
+	AwsRegion string `json:"awsRegion,omitempty"`
+	// AwsEndpoint Custom Route53 endpoint URL, for example a local moto server. It is used for STS too when RoleArn is set.
+	// Leave it empty to use the operator default (--aws-endpoint) or the public AWS endpoint
+	// +optional
+	AwsEndpoint string `json:"awsEndpoint,omitempty"`
+}
+
+// DnsProviderRef references the DnsProvider or the ClusterDnsProvider holding the credentials and the defaults of a record
+type DnsProviderRef struct {
+	// Name of the provider
+	Name string `json:"name"`
+	// Kind One of DnsProvider, in the namespace of the record, or ClusterDnsProvider. Defaults to DnsProvider
+	// +kubebuilder:validation:Enum=DnsProvider;ClusterDnsProvider
+	// +kubebuilder:default=DnsProvider
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// Record a dns record
+type Record struct {
+	// Name Fully Qualified Domain Name
+	Name string `json:"name"`
+	// Type One of the record types supported by the provider, like A, CNAME, TXT
+	Type string `json:"type"`
+	// Ttl time To live in seconds. Defaults to the defaultTtl of the provider, or 300
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=2147483647
+	// +optional
+	Ttl int64 `json:"ttl,omitempty"`
+	// Values The values of the record, in the zone file syntax. Not set for Route53 alias records
+	// +optional
+	Values []string `json:"values,omitempty"`
+	// Typed values of the MX, SRV, CAA, TXT and NAPTR records, instead of Values
+	StructuredValues `json:",inline"`
+	// Comment optional comment
+	// +optional
+	Comment string `json:"comment,omitempty"`
+	// Route53 options of the record specific to AWS Route53
+	// +optional
+	Route53 *Route53RecordOptions `json:"route53,omitempty"`
+}
+
+// StructuredValues typed values of a record, rendered by the dns provider in its wire format.
+// Set the field matching the type of the record
+type StructuredValues struct {
+	// MX Mail servers of a MX record
+	// +optional
+	MX []MXValue `json:"mx,omitempty"`
+	// SRV Services of a SRV record
+	// +optional
+	SRV []SRVValue `json:"srv,omitempty"`
+	// CAA Certificate authorities allowed to issue certificates, in a CAA record
+	// +optional
+	CAA []CAAValue `json:"caa,omitempty"`
+	// TXT Texts of a TXT or SPF record, without quotes: they are quoted, escaped and split in strings of 255 bytes
+	// +optional
+	TXT []string `json:"txt,omitempty"`
+	// NAPTR Rules of a NAPTR record
+	// +optional
+	NAPTR []NAPTRValue `json:"naptr,omitempty"`
+}
+
+// MXValue a mail server
+type MXValue struct {
+	// Priority The servers with the lowest priority are tried first
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Priority int32 `json:"priority"`
+	// Host Domain name of the mail server
+	Host string `json:"host"`
+}
+
+// SRVValue a server of a service
+type SRVValue struct {
+	// Priority The servers with the lowest priority are tried first
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Priority int32 `json:"priority"`
+	// Weight Share of the requests among the servers with the same priority
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Weight int32 `json:"weight"`
+	// Port Port of the service on the server
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// Target Domain name of the server
+	Target string `json:"target"`
+}
+
+// CAAValue a property of a CAA record
+type CAAValue struct {
+	// Flags 0, or 128 if the certificate authority must understand the tag
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	Flags int32 `json:"flags,omitempty"`
+	// Tag One of issue, issuewild, iodef
+	Tag string `json:"tag"`
+	// Value Like the domain of the certificate authority, or the url to report to for iodef
+	Value string `json:"value"`
+}
+
+// NAPTRValue a rule of a NAPTR record
+type NAPTRValue struct {
+	// Order The rules with the lowest order are processed first
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Order int32 `json:"order"`
+	// Preference Order of the rules with the same order
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	Preference int32 `json:"preference"`
+	// Flags Like U, S, A or P
+	// +optional
+	Flags string `json:"flags,omitempty"`
+	// Service Like SIP+D2U
+	// +optional
+	Service string `json:"service,omitempty"`
+	// Regexp Substitution expression applied to the client string
+	// +optional
+	Regexp string `json:"regexp,omitempty"`
+	// Replacement Domain name to query next. Defaults to "." when Regexp is used
+	// +optional
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Route53RecordOptions options of a record specific to AWS Route53
+type Route53RecordOptions struct {
+	// ZoneId AWS Route53 ZoneID. Leave it empty to use the hosted zone with the longest suffix match on the name
+	// +optional
+	ZoneId string `json:"zoneId,omitempty"`
+	// ZonePreference Which hosted zone to use when ZoneId is empty and both a public and a private zone match the name,
+	// one of Public, Private. Defaults to Public
+	// +kubebuilder:validation:Enum=Public;Private
+	// +optional
+	ZonePreference string `json:"zonePreference,omitempty"`
+	// Alias Route53 alias target, like an ELB, a CloudFront distribution or an S3 website.
+	// Mutually exclusive with the values of the record
+	// +optional
+	Alias *Route53Alias `json:"alias,omitempty"`
+	// Routing policy, to share the name and type with other records
+	Route53Routing `json:",inline"`
+}
+
+// Route53Alias points a record to an AWS resource. Alias records are allowed at the zone apex
+type Route53Alias struct {
+	// DNSName DNS name of the target, like the DNS name of the load balancer
+	DNSName string `json:"dnsName"`
+	// HostedZoneId Hosted zone of the target, like the canonical hosted zone of the load balancer
+	HostedZoneId string `json:"hostedZoneId"`
+	// EvaluateTargetHealth Route53 answers with the target only if it is healthy
+	// +optional
+	EvaluateTargetHealth bool `json:"evaluateTargetHealth,omitempty"`
+}
+
+// Route53Routing Route53 routing policy. Records with the same name and type are told apart by their SetIdentifier,
+// and must all use the same policy: one of Weight, Region, GeoLocation and Failover
+type Route53Routing struct {
+	// SetIdentifier Identifies the record among the records with the same name and type.
+	// Required with a routing policy
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	SetIdentifier string `json:"setIdentifier,omitempty"`
+	// Weight Weighted routing: share of the answers served by this record, relative to the other records
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	Weight *int64 `json:"weight,omitempty"`
+	// Region Latency routing: AWS region of the resource the record points to
+	// +optional
+	Region string `json:"region,omitempty"`
+	// GeoLocation Geolocation routing: location of the clients served by this record
+	// +optional
+	GeoLocation *Route53GeoLocation `json:"geoLocation,omitempty"`
+	// Failover Failover routing: one of PRIMARY, SECONDARY
+	// +kubebuilder:validation:Enum=PRIMARY;SECONDARY
+	// +optional
+	Failover string `json:"failover,omitempty"`
+	// HealthCheckId Id of the Route53 health check of the record. Mutually exclusive with HealthCheckRef
+	// +optional
+	HealthCheckId string `json:"healthCheckId,omitempty"`
+	// HealthCheckRef Name of the Route53HealthCheck, in the same namespace, of the record.
+	// Mutually exclusive with HealthCheckId
+	// +optional
+	HealthCheckRef string `json:"healthCheckRef,omitempty"`
+}
+
+// Route53GeoLocation Location of the clients served by a record. Set either the continent, or the country
+// (and optionally the subdivision). Use the country "*" for the default location
+type Route53GeoLocation struct {
+	// ContinentCode Two-letter continent code, like EU
+	// +optional
+	ContinentCode string `json:"continentCode,omitempty"`
+	// CountryCode Two-letter country code, like IT
+	// +optional
+	CountryCode string `json:"countryCode,omitempty"`
+	// SubdivisionCode Subdivision of the country, like the US state code
+	// +optional
+	SubdivisionCode string `json:"subdivisionCode,omitempty"`
+}
+
+// DnsRecordSpec defines the desired state of DnsRecord
+type DnsRecordSpec struct {
+	// ProviderRef The DnsProvider or ClusterDnsProvider holding the credentials and the defaults of the records.
+	// Mutually exclusive with Route53
+	// +optional
+	ProviderRef *DnsProviderRef `json:"providerRef,omitempty"`
+	// Route53 credentials of the Route53 records, when they don't reference a provider
+	// +optional
+	Route53 *Route53Credentials `json:"route53,omitempty"`
+	// Records The records managed by the DnsRecord. The conversion webhook rejects more than one record,
+	// until the reconciler applies each of them
+	// +kubebuilder:validation:MinItems=1
+	Records []Record `json:"records"`
+	// DriftPolicy What to do when the live record differs from the spec, one of Correct, Report.
+	// Defaults to Correct
+	// +kubebuilder:validation:Enum=Correct;Report
+	// +kubebuilder:default=Correct
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+	// AdoptPolicy What to do when the record already exists in the dns and is not owned by this DnsRecord,
+	// one of Never, IfUnowned, Always. Defaults to Never
+	// +kubebuilder:validation:Enum=Never;IfUnowned;Always
+	// +kubebuilder:default=Never
+	// +optional
+	AdoptPolicy string `json:"adoptPolicy,omitempty"`
+}
+
+// AppliedRecord identifies the record last applied to the dns provider
+type AppliedRecord struct {
+	// ZoneId AWS Route53 ZoneID
+	ZoneId string `json:"zoneId"`
+	// Name Fully Qualified Domain Name
+	Name string `json:"name"`
+	// Type Record type
+	Type string `json:"type"`
+	// Ttl time To live in seconds
+	// +optional
+	Ttl int64 `json:"ttl,omitempty"`
+	// ResourceRecords List of DNS target
+	// +optional
+	ResourceRecords []string `json:"resourceRecords,omitempty"`
+	// Alias Route53 alias target
+	// +optional
+	Alias *Route53Alias `json:"alias,omitempty"`
+	// Routing policy
+	Route53Routing `json:",inline"`
+}
+
+// DnsRecordStatus defines the observed state of DnsRecord
+type DnsRecordStatus struct {
+	// Status Short summary of the record state, one of Synced, Error, Deleting
+	// +optional
+	Status string `json:"status,omitempty"`
+	// ChangeId The id of the last change submitted to the dns provider
+	// +optional
+	ChangeId string `json:"changeId,omitempty"`
+	// ObservedGeneration The .metadata.generation last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastSyncTime Last time the record was applied to the dns provider
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// AppliedHash Hash of the spec last applied to the dns provider
+	// +optional
+	AppliedHash string `json:"appliedHash,omitempty"`
+	// AppliedRecord The record last applied to the dns provider, deleted when the record is renamed or removed
+	// +optional
+	AppliedRecord *AppliedRecord `json:"appliedRecord,omitempty"`
+	// Failures Number of consecutive failed attempts to apply the record, used to compute the retry backoff
+	// +optional
+	Failures int32 `json:"failures,omitempty"`
+	// Conditions Ready, Synced, Propagated and Drifted
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// DnsRecord is the Schema for the dnsrecords API
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Name",type=string,JSONPath=`.spec.records[0].name`
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.records[0].type`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type DnsRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DnsRecordSpec   `json:"spec,omitempty"`
+	Status DnsRecordStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// DnsRecordList contains a list of DnsRecord
+type DnsRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DnsRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DnsRecord{}, &DnsRecordList{})
+}
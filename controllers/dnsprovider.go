@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+)
+
+// ReasonZoneNotAllowed the name of the record is outside the zones allowed by its provider
+const ReasonZoneNotAllowed = "ZoneNotAllowed"
+
+// providerIndex indexes the DnsRecords by the kind/namespace/name of their provider
+const providerIndex = "spec.providerRef"
+
+// resolveProvider copies the credentials and the defaults of the provider referenced by the record into its spec.
+// Like resolveReferences, the spec is changed in memory only
+func resolveProvider(ctx context.Context, c client.Reader, crd *netv1alpha1.DnsRecord) error {
+	ref := crd.Spec.ProviderRef
+	if ref == nil {
+		return nil
+	}
+
+	spec := &crd.Spec.Route53Records
+	if err := checkProviderCredentials(*spec); err != nil {
+		return err
+	}
+
+	providerSpec, namespace, err := getDnsProvider(ctx, c, crd.Namespace, *ref)
+	if err != nil {
+		return err
+	}
+	if err := checkAllowedZones(*spec, *ref, providerSpec); err != nil {
+		return err
+	}
+
+	if spec.Ttl == 0 {
+		spec.Ttl = providerSpec.DefaultTtl
+	}
+	if route53 := providerSpec.Route53; route53 != nil {
+		spec.AwsSecrets = route53.AwsSecrets
+		if spec.AwsSecrets.SecretName != "" && spec.AwsSecrets.SecretNamespace == "" {
+			spec.AwsSecrets.SecretNamespace = namespace
+		}
+		spec.RoleArn = route53.RoleArn
+		spec.ExternalId = route53.ExternalId
+		if spec.AwsRegion == "" {
+			spec.AwsRegion = route53.AwsRegion
+		}
+		if spec.AwsEndpoint == "" {
+			spec.AwsEndpoint = route53.AwsEndpoint
+		}
+	}
+	return nil
+}
+
+// checkProviderCredentials the records referencing a provider use its credentials
+func checkProviderCredentials(spec netv1alpha1.Route53Record) error {
+	if spec.AwsSecrets.SecretName != "" || spec.RoleArn != "" || spec.ExternalId != "" {
+		return NewTerminalError(ReasonInvalidRecord, errors.New("awsSecrets, roleArn and externalId can't be set with providerRef"))
+	}
+	return nil
+}
+
+// checkAllowedZones the name of the record must be in the zones allowed by its provider
+func checkAllowedZones(spec netv1alpha1.Route53Record, ref netv1alpha1.DnsProviderRef, providerSpec *netv1alpha1.DnsProviderSpec) error {
+	if !zoneAllowed(spec.Name, providerSpec.AllowedZones) {
+		return NewTerminalError(ReasonZoneNotAllowed, fmt.Errorf("%s is not in the zones allowed by the %s %s: %s",
+			spec.Name, providerKind(ref), ref.Name, strings.Join(providerSpec.AllowedZones, ", ")))
+	}
+	return nil
+}
+
+// getDnsProvider returns the spec of the provider, and the namespace of its Secrets when they don't set one
+func getDnsProvider(ctx context.Context, c client.Reader, namespace string, ref netv1alpha1.DnsProviderRef) (*netv1alpha1.DnsProviderSpec, string, error) {
+	if providerKind(ref) == netv1alpha1.ClusterDnsProviderKind {
+		provider := &netv1alpha1.ClusterDnsProvider{}
+		if err := c.Get(ctx, client.ObjectKey{Name: ref.Name}, provider); err != nil {
+			return nil, "", fmt.Errorf("can't get the ClusterDnsProvider %s: %w", ref.Name, err)
+		}
+		route53 := provider.Spec.Route53
+		if route53 != nil && route53.AwsSecrets.SecretName != "" && route53.AwsSecrets.SecretNamespace == "" {
+			return nil, "", NewTerminalError(ReasonCredentialsError, fmt.Errorf("the ClusterDnsProvider %s must set the secretNamespace", ref.Name))
+		}
+		return &provider.Spec, "", nil
+	}
+
+	provider := &netv1alpha1.DnsProvider{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, provider); err != nil {
+		return nil, "", fmt.Errorf("can't get the DnsProvider %s: %w", ref.Name, err)
+	}
+	return &provider.Spec, namespace, nil
+}
+
+// providerKind the kind of the referenced provider, DnsProvider when not set
+func providerKind(ref netv1alpha1.DnsProviderRef) string {
+	if ref.Kind == "" {
+		return netv1alpha1.DnsProviderKind
+	}
+	return ref.Kind
+}
+
+// zoneAllowed true if name is one of the zones or a subdomain. Any name is allowed when there are no zones
+func zoneAllowed(name string, zones []string) bool {
+	if len(zones) == 0 {
+		return true
+	}
+	name = normalizeDnsName(name)
+	for _, zone := range zones {
+		zone = normalizeDnsName(zone)
+		if name == zone || strings.HasSuffix(name, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexProvider returns the kind/namespace/name of the provider of a DnsRecord
+func indexProvider(object client.Object) []string {
+	record, isRecord := object.(*netv1alpha1.DnsRecord)
+	if !isRecord || record.Spec.ProviderRef == nil {
+		return nil
+	}
+	ref := *record.Spec.ProviderRef
+	if providerKind(ref) == netv1alpha1.ClusterDnsProviderKind {
+		return []string{providerKey(netv1alpha1.ClusterDnsProviderKind, "", ref.Name)}
+	}
+	return []string{providerKey(netv1alpha1.DnsProviderKind, record.Namespace, ref.Name)}
+}
+
+// indexProviderSecret returns the namespace/name of the Secret holding the credentials of a DnsProvider
+// or a ClusterDnsProvider. The providers using the default credential chain are not indexed
+func indexProviderSecret(object client.Object) []string {
+	var spec netv1alpha1.DnsProviderSpec
+	switch provider := object.(type) {
+	case *netv1alpha1.DnsProvider:
+		spec = provider.Spec
+	case *netv1alpha1.ClusterDnsProvider:
+		spec = provider.Spec
+	default:
+		return nil
+	}
+	if spec.Route53 == nil || spec.Route53.AwsSecrets.SecretName == "" {
+		return nil
+	}
+	return []string{secretKey(object.GetNamespace(), spec.Route53.AwsSecrets)}
+}
+
+// providerKey the value of providerIndex
+func providerKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// dnsRecordsForDnsProvider returns the DnsRecords referencing the DnsProvider
+func (r *DnsRecordReconciler) dnsRecordsForDnsProvider(provider client.Object) []reconcile.Request {
+	return r.dnsRecordsIndexed(providerIndex, providerKey(netv1alpha1.DnsProviderKind, provider.GetNamespace(), provider.GetName()))
+}
+
+// dnsRecordsForClusterDnsProvider returns the DnsRecords referencing the ClusterDnsProvider
+func (r *DnsRecordReconciler) dnsRecordsForClusterDnsProvider(provider client.Object) []reconcile.Request {
+	return r.dnsRecordsIndexed(providerIndex, providerKey(netv1alpha1.ClusterDnsProviderKind, "", provider.GetName()))
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func newTestCredentialsSecret(namespace string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: namespace},
+		Data:       map[string][]byte{"id": []byte("AKID"), "secret": []byte("SECRET")},
+	}
+}
+
+func newTestProviderSpec() netv1alpha1.DnsProviderSpec {
+	return netv1alpha1.DnsProviderSpec{
+		Route53: &netv1alpha1.Route53ProviderSpec{
+			AwsSecrets: netv1alpha1.AwsSecret{SecretName: "aws", AccessKeyIDKey: "id", SecretAccessKeyKey: "secret"},
+		},
+		DefaultTtl:   60,
+		AllowedZones: []string{"example.com"},
+	}
+}
+
+func TestReconcileUsesDnsProvider(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	provider := &netv1alpha1.DnsProvider{ObjectMeta: metav1.ObjectMeta{Name: "route53", Namespace: "default"}, Spec: newTestProviderSpec()}
+	record := newTestRecord()
+	record.Spec.Route53Records.Ttl = 0
+	record.Spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "route53"}
+	r := newTestRoute53Reconciler(fake, record, provider, newTestCredentialsSecret("default"))
+
+	if _, updated := reconcileRecord(t, r, record); updated.Status.Status != StatusSynced {
+		t.Fatalf("expected the record to be synced with the credentials of the provider, got %+v", updated.Status.Conditions)
+	}
+	if !strings.Contains(fake.authorizations[0], "Credential=AKID/") {
+		t.Errorf("expected the credentials of the provider, got %s", fake.authorizations[0])
+	}
+	changes := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")
+	if len(changes) != 1 || !strings.Contains(changes[0], "<TTL>60</TTL>") {
+		t.Errorf("expected the default ttl of the provider: %v", changes)
+	}
+	if requests := r.dnsRecordsForDnsProvider(provider); len(requests) != 1 || requests[0].Name != record.Name {
+		t.Errorf("the DnsRecord referencing the provider must be reconciled: %v", requests)
+	}
+}
+
+func TestIndexProviderSecret(t *testing.T) {
+	provider := &netv1alpha1.DnsProvider{ObjectMeta: metav1.ObjectMeta{Name: "route53", Namespace: "team-a"}, Spec: newTestProviderSpec()}
+	if keys := indexProviderSecret(provider); len(keys) != 1 || keys[0] != "team-a/aws" {
+		t.Errorf("expected the secret in the namespace of the provider, got %v", keys)
+	}
+
+	spec := newTestProviderSpec()
+	spec.Route53.AwsSecrets.SecretNamespace = "dns-system"
+	clusterProvider := &netv1alpha1.ClusterDnsProvider{ObjectMeta: metav1.ObjectMeta{Name: "route53"}, Spec: spec}
+	if keys := indexProviderSecret(clusterProvider); len(keys) != 1 || keys[0] != "dns-system/aws" {
+		t.Errorf("expected the secret namespace of the credentials, got %v", keys)
+	}
+
+	clusterProvider.Spec.Route53 = nil
+	if keys := indexProviderSecret(clusterProvider); len(keys) != 0 {
+		t.Errorf("a provider without a secret must not be indexed, got %v", keys)
+	}
+}
+
+func TestReconcileUsesClusterDnsProvider(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	spec := newTestProviderSpec()
+	spec.Route53.AwsSecrets.SecretNamespace = "dns-system"
+	provider := &netv1alpha1.ClusterDnsProvider{ObjectMeta: metav1.ObjectMeta{Name: "route53"}, Spec: spec}
+	record := newTestRecord()
+	record.Namespace = "team-a"
+	record.Spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "route53", Kind: netv1alpha1.ClusterDnsProviderKind}
+	r := newTestRoute53Reconciler(fake, record, provider, newTestCredentialsSecret("dns-system"))
+
+	if _, updated := reconcileRecord(t, r, record); updated.Status.Status != StatusSynced {
+		t.Fatalf("a ClusterDnsProvider may use a secret in any namespace, got %+v", updated.Status.Conditions)
+	}
+}
+
+func TestReconcileRejectsRecordsOutsideProvider(t *testing.T) {
+	for name, change := range map[string]func(record *netv1alpha1.DnsRecord){
+		"credentials": func(record *netv1alpha1.DnsRecord) {
+			record.Spec.Route53Records.AwsSecrets = netv1alpha1.AwsSecret{SecretName: "other"}
+		},
+		"zone": func(record *netv1alpha1.DnsRecord) {
+			record.Spec.Route53Records.Name = "www.example.org"
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := newFakeRoute53(t)
+			provider := &netv1alpha1.DnsProvider{ObjectMeta: metav1.ObjectMeta{Name: "route53", Namespace: "default"}, Spec: newTestProviderSpec()}
+			record := newTestRecord()
+			record.Spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "route53"}
+			change(record)
+			r := newTestRoute53Reconciler(fake, record, provider, newTestCredentialsSecret("default"))
+
+			result, updated := reconcileRecord(t, r, record)
+			synced := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionSynced)
+			if synced == nil || synced.Status != metav1.ConditionFalse || (synced.Reason != ReasonInvalidRecord && synced.Reason != ReasonZoneNotAllowed) {
+				t.Errorf("expected the record to be rejected, got %+v", synced)
+			}
+			if result.RequeueAfter != 0 || len(fake.authorizations) != 0 {
+				t.Errorf("a rejected record must not be retried nor applied")
+			}
+		})
+	}
+}
+
+func TestReconcileWaitsForDnsProvider(t *testing.T) {
+	record := newTestRecord()
+	record.Spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "missing"}
+	r := newTestRoute53Reconciler(newFakeRoute53(t), record)
+
+	result, updated := reconcileRecord(t, r, record)
+	if synced := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionSynced); synced == nil || synced.Reason != ReasonReferenceNotReady {
+		t.Errorf("expected a ReferenceNotReady condition, got %+v", synced)
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("a missing provider must be retried")
+	}
+}
+
+func TestZoneAllowed(t *testing.T) {
+	zones := []string{"example.com."}
+	for name, allowed := range map[string]bool{
+		"example.com":          true,
+		"WWW.Example.com.":     true,
+		"a.b.example.com":      true,
+		"badexample.com":       false,
+		"www.example.com.evil": false,
+	} {
+		if zoneAllowed(name, zones) != allowed {
+			t.Errorf("zoneAllowed(%s) != %v", name, allowed)
+		}
+	}
+	if !zoneAllowed("anything.org", nil) {
+		t.Error("any name is allowed without zones")
+	}
+}
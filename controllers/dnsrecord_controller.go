@@ -25,9 +25,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"time"
 )
 
@@ -35,13 +39,23 @@ import (
 type DnsRecordReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Providers the dns backends available to the reconciler
+	Providers *ProviderRegistry
+	// ResyncPeriod how often the live records are compared with the spec. Zero disables the resync
+	ResyncPeriod time.Duration
 }
 
 const dnsRecordFinalizer = "dnsrecord.net.beekube.cloud/finalizer"
 
+// propagationPollInterval how often a pending change is checked
+const propagationPollInterval = 10 * time.Second
+
 // +kubebuilder:rbac:groups=net.beekube.cloud,resources=dnsrecords,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=net.beekube.cloud,resources=dnsrecords/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=net.beekube.cloud,resources=dnsrecords/finalizers,verbs=update
+// +kubebuilder:rbac:groups=net.beekube.cloud,resources=route53healthchecks,verbs=get;list;watch
+// +kubebuilder:rbac:groups=net.beekube.cloud,resources=dnsproviders,verbs=get;list;watch
+// +kubebuilder:rbac:groups=net.beekube.cloud,resources=clusterdnsproviders,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;create
 
@@ -63,26 +77,44 @@ func (r *DnsRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, errGetCrd
 	}
 
-	var errApi error
-	r.LogEvent(ctx, "Normal", "InitReconciliation", "Upserting dns record", req, crd)
+	provider := r.Providers.For(crd)
 
 	// Resource deletion
 	if crd.GetDeletionTimestamp() != nil {
 		logger.Info("Cleaning up records")
 		if controllerutil.ContainsFinalizer(crd, dnsRecordFinalizer) {
 			logger.Info("Found a finalizer")
+			if crd.Status.Status != StatusDeleting {
+				crd.Status.Status = StatusDeleting
+				setCondition(crd, netv1alpha1.ConditionReady, metav1.ConditionFalse, ReasonDeleting, "Record is being deleted")
+				_ = r.updateStatus(ctx, crd)
+			}
 
-			if crd.Spec.Route53Records.Name != "" {
-				if err := r.FinalizeAwsRoute53(ctx, req.Namespace, crd.Spec.Route53Records); err != nil {
+			if provider != nil {
+				// The credentials of the provider are needed to delete the record, but must not be persisted
+				// when the finalizer is removed
+				resolved := crd.DeepCopy()
+				err := resolveProvider(ctx, r, resolved)
+				if err == nil {
+					err = provider.Delete(ctx, resolved)
+				}
+				if err != nil {
 					// Run finalization logic for memcachedFinalizer. If the
 					// finalization logic fails, don't remove the finalizer so
 					// that we can retry during the next reconciliation.
 					logger.Error(err, "can't cleanup - retry later")
 					r.LogEvent(ctx, "Warning", "ErrorDnsApiFinalize", err.Error(), req, crd)
-					return RequeueAfter(120 * time.Second)
+					providerError := ClassifyError(err)
+					recordProviderError(provider, providerError)
+					crd.Status.Failures++
+					setCondition(crd, netv1alpha1.ConditionSynced, metav1.ConditionFalse, providerError.Reason, err.Error())
+					_ = r.updateStatus(ctx, crd)
+					return RequeueAfter(backoff(crd.Status.Failures))
 				}
 			}
 
+			forgetDrift(crd)
+
 			// Remove the finalizer. Once all finalizers have been
 			// removed, the object will be deleted.
 			controllerutil.RemoveFinalizer(crd, dnsRecordFinalizer)
@@ -97,56 +129,265 @@ func (r *DnsRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	// Resource Upsert
-	if crd.Spec.Route53Records.Name != "" {
-		// It's an aws record!
-		// Resource creation
-		errApi = r.ReconcileRoute53(ctx, req.Namespace, crd.Spec.Route53Records)
+	if provider == nil {
+		r.LogEvent(ctx, "Warning", "NoProvider", "No dns provider can handle this record", req, crd)
+		setFailed(crd, ReasonNoProvider, "No dns provider can handle this record")
+		_ = r.updateStatus(ctx, crd)
+		return DoNotRequeue()
+	}
+
+	if err := r.resolveReferences(ctx, crd); err != nil {
+		if !ClassifyError(err).Retryable {
+			return r.handleProviderError(ctx, req, crd, provider, err)
+		}
+		r.LogEvent(ctx, "Warning", ReasonReferenceNotReady, err.Error(), req, crd)
+		setFailed(crd, ReasonReferenceNotReady, err.Error())
+		crd.Status.Failures++
+		_ = r.updateStatus(ctx, crd)
+		return RequeueAfter(backoff(crd.Status.Failures))
+	}
+	if resolver, isResolver := provider.(Resolver); isResolver {
+		if err := resolver.Resolve(ctx, crd); err != nil {
+			return r.handleProviderError(ctx, req, crd, provider, err)
+		}
+	}
+
+	// The finalizer is added after the first apply: if that failed, the record must not be skipped below
+	// without it, otherwise deleting the DnsRecord would leave the live record behind
+	if isApplied(crd) {
+		if err := r.ensureFinalizer(ctx, req, crd); err != nil {
+			return RequeueWithError(err)
+		}
+	}
+
+	// The spec has already been applied, wait for the change to propagate instead of submitting it again
+	if isChangePending(crd) {
+		return r.pollChange(ctx, req, crd, provider)
+	}
+
+	// The spec has already been applied: don't call the provider until the next resync,
+	// then check that nobody changed the live record
+	if isApplied(crd) {
+		if due, wait := r.resyncDue(crd); !due {
+			if crd.Status.ObservedGeneration != crd.Generation {
+				crd.Status.ObservedGeneration = crd.Generation
+				_ = r.updateStatus(ctx, crd)
+			}
+			if wait <= 0 {
+				return DoNotRequeue()
+			}
+			return RequeueAfter(wait)
+		}
+
+		correct, result, err := r.checkDrift(ctx, req, crd, provider)
+		if !correct {
+			return result, err
+		}
 	}
 
+	r.LogEvent(ctx, "Normal", "InitReconciliation", "Upserting dns record", req, crd)
+	changeId, errApi := provider.Apply(ctx, crd)
 	if errApi != nil {
-		r.LogEvent(ctx, "Warning", "ErrorDnsApi", errApi.Error(), req, crd)
-		return DoNotRequeue()
+		return r.handleProviderError(ctx, req, crd, provider, errApi)
 	}
 
 	r.LogEvent(ctx, "Normal", "DnsApiUpdated", "DNS Record updated", req, crd)
+	setSynced(crd, changeId)
+	if errStatus := r.updateStatus(ctx, crd); errStatus != nil {
+		return RequeueWithError(errStatus)
+	}
 
-	if !controllerutil.ContainsFinalizer(crd, dnsRecordFinalizer) {
-		controllerutil.AddFinalizer(crd, dnsRecordFinalizer)
-		r.LogEvent(ctx, "Normal", "InternalApi", "Adding record finalizer - the dns will probably be updated again", req, crd)
-		errUpdate := r.Update(ctx, crd)
-		if errUpdate != nil {
-			logger.Error(errUpdate, "can't add finalizer - won't retry")
-			r.LogEvent(ctx, "Warning", "ErrorDnsApiFinalize", errUpdate.Error(), req, crd)
+	if err := r.ensureFinalizer(ctx, req, crd); err != nil {
+		return RequeueWithError(err)
+	}
+
+	if isChangePending(crd) {
+		return RequeueAfter(propagationPollInterval)
+	}
+
+	return r.requeueForResync()
+}
+
+// ensureFinalizer adds the finalizer if it is missing. The error is returned, so that the request is requeued
+func (r *DnsRecordReconciler) ensureFinalizer(ctx context.Context, req ctrl.Request, crd *netv1alpha1.DnsRecord) error {
+	if controllerutil.ContainsFinalizer(crd, dnsRecordFinalizer) {
+		return nil
+	}
+
+	// Patch a copy, not Update: the spec in memory has the references resolved
+	patched := crd.DeepCopy()
+	controllerutil.AddFinalizer(patched, dnsRecordFinalizer)
+	r.LogEvent(ctx, "Normal", "InternalApi", "Adding record finalizer", req, crd)
+	if err := r.Patch(ctx, patched, client.MergeFrom(crd)); err != nil {
+		log.FromContext(ctx).Error(err, "can't add finalizer - retry later")
+		r.LogEvent(ctx, "Warning", "ErrorDnsApiFinalize", err.Error(), req, crd)
+		return err
+	}
+	controllerutil.AddFinalizer(crd, dnsRecordFinalizer)
+	crd.ResourceVersion = patched.ResourceVersion
+	return nil
+}
+
+// checkDrift compares the live record with the spec. It returns true if the drift must be corrected
+// by applying the spec again, otherwise the result of the reconciliation
+func (r *DnsRecordReconciler) checkDrift(ctx context.Context, req ctrl.Request, crd *netv1alpha1.DnsRecord, provider Provider) (bool, ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	current, err := provider.Current(ctx, crd)
+	if err != nil {
+		providerError := ClassifyError(err)
+		recordProviderError(provider, providerError)
+		logger.Error(err, "can't get the live record - retry later")
+		result, errResult := RequeueAfter(backoff(crd.Status.Failures + 1))
+		return false, result, errResult
+	}
+
+	diff := diffRecordSets(desiredRecordSet(crd), current)
+	if diff == "" {
+		recordDrift(crd, false)
+		now := metav1.Now()
+		crd.Status.LastSyncTime = &now
+		setCondition(crd, netv1alpha1.ConditionDrifted, metav1.ConditionFalse, ReasonInSync, "The live record matches the spec")
+		// A drift reported earlier may have been fixed by hand
+		setCondition(crd, netv1alpha1.ConditionReady, metav1.ConditionTrue, ReasonInSync, "The live record matches the spec")
+		if errStatus := r.updateStatus(ctx, crd); errStatus != nil {
+			return false, ctrl.Result{}, errStatus
+		}
+		result, errResult := r.requeueForResync()
+		return false, result, errResult
+	}
+
+	policy := crd.Spec.DriftPolicy
+	if policy == "" {
+		policy = netv1alpha1.DriftPolicyCorrect
+	}
+	r.LogEvent(ctx, "Warning", "DriftDetected", diff, req, crd)
+	driftDetectedTotal.WithLabelValues(policy).Inc()
+	if policy == netv1alpha1.DriftPolicyReport {
+		recordDrift(crd, true)
+		setCondition(crd, netv1alpha1.ConditionDrifted, metav1.ConditionTrue, ReasonDriftDetected, diff)
+		setCondition(crd, netv1alpha1.ConditionReady, metav1.ConditionFalse, ReasonDriftDetected, diff)
+		if errStatus := r.updateStatus(ctx, crd); errStatus != nil {
+			return false, ctrl.Result{}, errStatus
 		}
+		result, errResult := r.requeueForResync()
+		return false, result, errResult
 	}
 
-	return DoNotRequeue()
+	recordDrift(crd, false)
+	setCondition(crd, netv1alpha1.ConditionDrifted, metav1.ConditionFalse, ReasonDriftCorrected, "Corrected: "+diff)
+	return true, ctrl.Result{}, nil
 }
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *DnsRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&netv1alpha1.DnsRecord{}).
-		Complete(r)
+// resyncDue returns true if the live record must be compared with the spec,
+// otherwise how long to wait before the next comparison (zero if the resync is disabled)
+func (r *DnsRecordReconciler) resyncDue(crd *netv1alpha1.DnsRecord) (bool, time.Duration) {
+	if r.ResyncPeriod <= 0 {
+		return false, 0
+	}
+	if crd.Status.LastSyncTime == nil {
+		return true, 0
+	}
+	wait := r.ResyncPeriod - time.Since(crd.Status.LastSyncTime.Time)
+	return wait <= 0, wait
 }
 
-func (r *DnsRecordReconciler) GetSecret(ctx context.Context, ns string, secretName string, secretKey string) (string, error) {
-	awsSecret := v1.Secret{}
-	errGetSecret := r.Get(ctx, client.ObjectKey{
-		Namespace: ns,
-		Name:      secretName,
-	}, &awsSecret)
+// requeueForResync schedules the next comparison between the live record and the spec
+func (r *DnsRecordReconciler) requeueForResync() (ctrl.Result, error) {
+	if r.ResyncPeriod <= 0 {
+		return DoNotRequeue()
+	}
+	return RequeueAfter(r.ResyncPeriod)
+}
 
-	if errGetSecret != nil {
-		return "", errGetSecret
+// handleProviderError records the error in the status. Retryable errors are retried with an exponential
+// backoff, terminal errors are not retried until the record changes
+func (r *DnsRecordReconciler) handleProviderError(ctx context.Context, req ctrl.Request, crd *netv1alpha1.DnsRecord, provider Provider, err error) (ctrl.Result, error) {
+	providerError := ClassifyError(err)
+	recordProviderError(provider, providerError)
+	r.LogEvent(ctx, "Warning", "ErrorDnsApi", err.Error(), req, crd)
+	setFailed(crd, providerError.Reason, err.Error())
+
+	if !providerError.Retryable {
+		crd.Status.Failures = 0
+		_ = r.updateStatus(ctx, crd)
+		return DoNotRequeue()
 	}
 
-	data, hasData := awsSecret.Data[secretKey]
-	if !hasData {
-		return "", fmt.Errorf("secret key %s not found", secretKey)
+	crd.Status.Failures++
+	_ = r.updateStatus(ctx, crd)
+	return RequeueAfter(backoff(crd.Status.Failures))
+}
+
+// pollChange checks if the pending change has propagated, and requeues the request until it has.
+// Retryable errors are retried with an exponential backoff; terminal errors stop the polling,
+// and the record waits for the next resync
+func (r *DnsRecordReconciler) pollChange(ctx context.Context, req ctrl.Request, crd *netv1alpha1.DnsRecord, provider Provider) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	inSync, err := provider.ChangeStatus(ctx, crd, crd.Status.ChangeId)
+	if err != nil {
+		providerError := ClassifyError(err)
+		recordProviderError(provider, providerError)
+		logger.Error(err, "can't get the change status", "changeId", crd.Status.ChangeId)
+		switch {
+		case providerError.Reason == ReasonChangeNotFound:
+			// The provider forgets the old changes: the record has been applied, but whether it is live is unknown
+			setCondition(crd, netv1alpha1.ConditionPropagated, metav1.ConditionUnknown, ReasonChangeNotFound, err.Error())
+		case !providerError.Retryable:
+			r.LogEvent(ctx, "Warning", "ErrorDnsApi", err.Error(), req, crd)
+			setCondition(crd, netv1alpha1.ConditionPropagated, metav1.ConditionFalse, providerError.Reason, err.Error())
+		default:
+			crd.Status.Failures++
+			_ = r.updateStatus(ctx, crd)
+			return RequeueAfter(backoff(crd.Status.Failures))
+		}
+		crd.Status.Failures = 0
+		if errStatus := r.updateStatus(ctx, crd); errStatus != nil {
+			return RequeueWithError(errStatus)
+		}
+		return r.requeueForResync()
+	}
+	if !inSync {
+		return RequeueAfter(propagationPollInterval)
+	}
+
+	r.LogEvent(ctx, "Normal", "DnsApiPropagated", "DNS Record propagated", req, crd)
+	crd.Status.Failures = 0
+	setCondition(crd, netv1alpha1.ConditionPropagated, metav1.ConditionTrue, ReasonChangeInSync, "Change "+crd.Status.ChangeId+" is live")
+	if errStatus := r.updateStatus(ctx, crd); errStatus != nil {
+		return RequeueWithError(errStatus)
+	}
+
+	return r.requeueForResync()
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Updates that don't change the generation (status, finalizers, metadata) are ignored,
+// otherwise every status update would trigger a new reconciliation.
+// The DnsRecords referencing a health check are reconciled when its status changes,
+// the ones using a Secret as credentials, directly or through their provider, or a provider when they change
+func (r *DnsRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &netv1alpha1.DnsRecord{}, secretIndex, indexSecret); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &netv1alpha1.DnsRecord{}, providerIndex, indexProvider); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &netv1alpha1.DnsProvider{}, secretIndex, indexProviderSecret); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &netv1alpha1.ClusterDnsProvider{}, secretIndex, indexProviderSecret); err != nil {
+		return err
 	}
 
-	return string(data), nil
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&netv1alpha1.DnsRecord{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &netv1alpha1.Route53HealthCheck{}}, handler.EnqueueRequestsFromMapFunc(r.dnsRecordsForHealthCheck)).
+		Watches(&source.Kind{Type: &v1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.dnsRecordsForSecret)).
+		Watches(&source.Kind{Type: &netv1alpha1.DnsProvider{}}, handler.EnqueueRequestsFromMapFunc(r.dnsRecordsForDnsProvider)).
+		Watches(&source.Kind{Type: &netv1alpha1.ClusterDnsProvider{}}, handler.EnqueueRequestsFromMapFunc(r.dnsRecordsForClusterDnsProvider)).
+		Complete(r)
 }
 
 // LogEvent Creates an Event resource. Level must be either Normal or Warning
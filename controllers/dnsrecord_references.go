@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ReasonReferenceNotReady a resource referenced by the DnsRecord does not exist or is not ready
+const ReasonReferenceNotReady = "ReferenceNotReady"
+
+// secretIndex indexes the DnsRecords by the namespace/name of their credentials Secret
+const secretIndex = "spec.route53Records.awsSecrets"
+
+// resolveReferences replaces the references to other resources with the values they point to.
+// The spec is changed in memory only: it must not be persisted with Update (the status subresource ignores it)
+func (r *DnsRecordReconciler) resolveReferences(ctx context.Context, crd *netv1alpha1.DnsRecord) error {
+	if err := resolveProvider(ctx, r, crd); err != nil {
+		return err
+	}
+
+	routing := &crd.Spec.Route53Records.Route53Routing
+	if routing.HealthCheckRef == "" {
+		return nil
+	}
+
+	healthCheck := &netv1alpha1.Route53HealthCheck{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: crd.Namespace, Name: routing.HealthCheckRef}, healthCheck); err != nil {
+		return fmt.Errorf("can't get the health check %s: %w", routing.HealthCheckRef, err)
+	}
+	if healthCheck.Status.HealthCheckId == "" {
+		return fmt.Errorf("the health check %s has not been created yet", routing.HealthCheckRef)
+	}
+
+	routing.HealthCheckId = healthCheck.Status.HealthCheckId
+	routing.HealthCheckRef = ""
+	return nil
+}
+
+// dnsRecordsForHealthCheck returns the DnsRecords referencing the health check
+func (r *DnsRecordReconciler) dnsRecordsForHealthCheck(healthCheck client.Object) []reconcile.Request {
+	records := &netv1alpha1.DnsRecordList{}
+	if err := r.List(context.Background(), records, client.InNamespace(healthCheck.GetNamespace())); err != nil {
+		log.Log.Error(err, "can't list the DnsRecords referencing the health check", "healthCheck", healthCheck.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, record := range records.Items {
+		if record.Spec.Route53Records.HealthCheckRef == healthCheck.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&record)})
+		}
+	}
+	return requests
+}
+
+// indexSecret returns the namespace/name of the Secret holding the credentials of a DnsRecord.
+// The records using the default credential chain are not indexed
+func indexSecret(object client.Object) []string {
+	record, isRecord := object.(*netv1alpha1.DnsRecord)
+	if !isRecord || record.Spec.Route53Records.AwsSecrets.SecretName == "" {
+		return nil
+	}
+	return []string{secretKey(record.Namespace, record.Spec.Route53Records.AwsSecrets)}
+}
+
+// secretKey the value of secretIndex. The Secret is in the namespace of the record, unless the credentials set one
+func secretKey(namespace string, secrets netv1alpha1.AwsSecret) string {
+	if secrets.SecretNamespace != "" {
+		namespace = secrets.SecretNamespace
+	}
+	return namespace + "/" + secrets.SecretName
+}
+
+// dnsRecordsForSecret returns the DnsRecords using the Secret as credentials, directly or through their provider,
+// in any namespace, so that a Secret created late or rotated fixes the records that failed
+func (r *DnsRecordReconciler) dnsRecordsForSecret(secret client.Object) []reconcile.Request {
+	key := secret.GetNamespace() + "/" + secret.GetName()
+	requests := r.dnsRecordsIndexed(secretIndex, key)
+
+	providers := &netv1alpha1.DnsProviderList{}
+	if err := r.List(context.Background(), providers, client.MatchingFields{secretIndex: key}); err != nil {
+		log.Log.Error(err, "can't list the DnsProviders using the secret", "secret", key)
+	}
+	for i := range providers.Items {
+		requests = append(requests, r.dnsRecordsForDnsProvider(&providers.Items[i])...)
+	}
+
+	clusterProviders := &netv1alpha1.ClusterDnsProviderList{}
+	if err := r.List(context.Background(), clusterProviders, client.MatchingFields{secretIndex: key}); err != nil {
+		log.Log.Error(err, "can't list the ClusterDnsProviders using the secret", "secret", key)
+	}
+	for i := range clusterProviders.Items {
+		requests = append(requests, r.dnsRecordsForClusterDnsProvider(&clusterProviders.Items[i])...)
+	}
+	return requests
+}
+
+// dnsRecordsIndexed returns the DnsRecords, in any namespace, with the given value of the field index
+func (r *DnsRecordReconciler) dnsRecordsIndexed(index, value string) []reconcile.Request {
+	records := &netv1alpha1.DnsRecordList{}
+	if err := r.List(context.Background(), records, client.MatchingFields{index: value}); err != nil {
+		log.Log.Error(err, "can't list the DnsRecords", "index", index, "value", value)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(records.Items))
+	for _, record := range records.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&record)})
+	}
+	return requests
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
+)
+
+// +kubebuilder:webhook:path=/mutate-net-beekube-cloud-v1alpha1-dnsrecord,mutating=true,failurePolicy=fail,sideEffects=None,groups=net.beekube.cloud,resources=dnsrecords,verbs=create;update,versions=v1alpha1,name=mdnsrecord.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-net-beekube-cloud-v1alpha1-dnsrecord,mutating=false,failurePolicy=fail,sideEffects=None,groups=net.beekube.cloud,resources=dnsrecords,verbs=create;update,versions=v1alpha1,name=vdnsrecord.kb.io,admissionReviewVersions=v1
+
+// DnsRecordWebhook defaults the DnsRecords, so that the stored record is the one applied, and rejects
+// the records the dns provider would reject, so that kubectl apply fails instead of the reconciliation
+type DnsRecordWebhook struct {
+	client.Client
+	// Route53 finds the name of the hosted zone of the records. Leave it nil to skip the checks that need it
+	Route53 *Route53Provider
+}
+
+var _ admission.CustomDefaulter = &DnsRecordWebhook{}
+var _ admission.CustomValidator = &DnsRecordWebhook{}
+
+// SetupWebhookWithManager registers the webhook with the Manager
+func (w *DnsRecordWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&netv1alpha1.DnsRecord{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default normalizes the name to a lowercase fully qualified domain name and fills in the ttl,
+// the namespace of the credentials Secret and the comment.
+// It is applied to every update too, so it must not change a record already defaulted
+func (w *DnsRecordWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	record := obj.(*netv1alpha1.DnsRecord)
+	spec := &record.Spec.Route53Records
+
+	// Without its provider the ttl is left to the reconciler, that uses the default of the provider
+	var providerSpec *netv1alpha1.DnsProviderSpec
+	if ref := record.Spec.ProviderRef; ref != nil {
+		providerSpec, _, _ = getDnsProvider(ctx, w, record.Namespace, *ref)
+	}
+	// Relative names are expanded against the zone allowed by the provider, or the hosted zone of the record
+	zone := ""
+	if providerSpec != nil && len(providerSpec.AllowedZones) == 1 {
+		zone = providerSpec.AllowedZones[0]
+	}
+	if zone == "" && isRelativeName(spec.Name) {
+		zone = w.zoneName(ctx, record)
+	}
+
+	spec.Name = fqdn(spec.Name, zone)
+	if spec.Ttl == 0 && spec.Alias == nil && (record.Spec.ProviderRef == nil || providerSpec != nil) {
+		spec.Ttl = netv1alpha1.DefaultTtl
+		if providerSpec != nil && providerSpec.DefaultTtl != 0 {
+			spec.Ttl = providerSpec.DefaultTtl
+		}
+	}
+	if spec.AwsSecrets.SecretName != "" && spec.AwsSecrets.SecretNamespace == "" {
+		spec.AwsSecrets.SecretNamespace = record.Namespace
+	}
+	if spec.Comment == "" && record.Name != "" {
+		spec.Comment = record.Namespace + "/" + record.Name
+	}
+	return nil
+}
+
+// fqdn returns name as a lowercase domain name ending with a dot. A relative name, without the final dot
+// and outside the zone, is expanded against it. Without a zone a single label is left relative,
+// so that the validation rejects it instead of turning it into a top-level domain
+func fqdn(name, zone string) string {
+	relative := !strings.HasSuffix(name, ".")
+	name = normalizeDnsName(name)
+	if name == "" {
+		return name
+	}
+	if relative && zone != "" && !zoneAllowed(name, []string{zone}) {
+		name += "." + normalizeDnsName(zone)
+	}
+	if relative && zone == "" && !strings.Contains(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// isRelativeName returns true if the name does not end with a dot. The names like www.example.com
+// are fully qualified when the zone is unknown
+func isRelativeName(name string) bool {
+	return name != "" && !strings.HasSuffix(name, ".")
+}
+
+func (w *DnsRecordWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return w.validate(ctx, obj.(*netv1alpha1.DnsRecord))
+}
+
+// ValidateUpdate validates the records whose spec changes. The records created before the webhook
+// must still accept the finalizer and status updates, and be deleted
+func (w *DnsRecordWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	record := newObj.(*netv1alpha1.DnsRecord)
+	if record.DeletionTimestamp != nil || reflect.DeepEqual(oldObj.(*netv1alpha1.DnsRecord).Spec, record.Spec) {
+		return nil
+	}
+	return w.validate(ctx, record)
+}
+
+func (w *DnsRecordWebhook) ValidateDelete(_ context.Context, _ runtime.Object) error {
+	return nil
+}
+
+func (w *DnsRecordWebhook) validate(ctx context.Context, record *netv1alpha1.DnsRecord) error {
+	spec := record.Spec.Route53Records
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	if err := w.validateProvider(ctx, record); err != nil {
+		return err
+	}
+	if isRelativeName(spec.Name) && !strings.Contains(spec.Name, ".") {
+		return fmt.Errorf("%s is a relative name and the zone of the record is unknown: set the fully qualified name", spec.Name)
+	}
+
+	if zone := w.zoneName(ctx, record); zone != "" && !zoneAllowed(spec.Name, []string{zone}) {
+		return fmt.Errorf("%s is outside the hosted zone %s (%s)", spec.Name, spec.ZoneId, zone)
+	}
+	return nil
+}
+
+// zoneName returns the name of the hosted zone set by the zone id of the record, empty if it can't be found.
+// The webhook does not fail when Route53 can't be reached: the reconciler reports the error
+func (w *DnsRecordWebhook) zoneName(ctx context.Context, record *netv1alpha1.DnsRecord) string {
+	if w.Route53 == nil || record.Spec.Route53Records.ZoneId == "" {
+		return ""
+	}
+
+	// The provider holds the credentials
+	resolved := record.DeepCopy()
+	if err := resolveProvider(ctx, w, resolved); err != nil {
+		return ""
+	}
+	zone, err := w.Route53.ZoneName(ctx, resolved)
+	if err != nil {
+		log.FromContext(ctx).Info("can't find the hosted zone of the record", "zoneId", resolved.Spec.Route53Records.ZoneId, "error", err.Error())
+		return ""
+	}
+	return zone
+}
+
+// validateProvider checks the record against the provider it references
+func (w *DnsRecordWebhook) validateProvider(ctx context.Context, record *netv1alpha1.DnsRecord) error {
+	spec := record.Spec.Route53Records
+	ref := record.Spec.ProviderRef
+	if ref == nil {
+		return nil
+	}
+	if err := checkProviderCredentials(spec); err != nil {
+		return err
+	}
+	// The provider may be created after the record: the reconciler waits for it
+	providerSpec, _, err := getDnsProvider(ctx, w, record.Namespace, *ref)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return checkAllowedZones(spec, *ref, providerSpec)
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"context"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+)
+
+func TestDnsRecordValidator(t *testing.T) {
+	provider := &netv1alpha1.DnsProvider{ObjectMeta: metav1.ObjectMeta{Name: "route53", Namespace: "default"}, Spec: newTestProviderSpec()}
+	validator := &DnsRecordWebhook{Client: newTestReconciler(newMemoryProvider(), provider).Client}
+
+	for name, test := range map[string]struct {
+		change func(spec *netv1alpha1.DnsRecordSpec)
+		valid  bool
+	}{
+		"valid":        {func(spec *netv1alpha1.DnsRecordSpec) {}, true},
+		"wildcard":     {func(spec *netv1alpha1.DnsRecordSpec) { spec.Route53Records.Name = "*.example.com." }, true},
+		"bad name":     {func(spec *netv1alpha1.DnsRecordSpec) { spec.Route53Records.Name = "www..example.com" }, false},
+		"bad label":    {func(spec *netv1alpha1.DnsRecordSpec) { spec.Route53Records.Name = "-www.example.com" }, false},
+		"unknown type": {func(spec *netv1alpha1.DnsRecordSpec) { spec.Route53Records.Type = "cname" }, false},
+		"two cname values": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.ResourceRecords = []string{"a.example.com", "b.example.com"}
+		}, false},
+		"ipv4": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "A"
+			spec.Route53Records.ResourceRecords = []string{"10.0.0.1", "10.0.0.2"}
+		}, true},
+		"hostname as ipv4": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "A"
+			spec.Route53Records.ResourceRecords = []string{"lb.example.com"}
+		}, false},
+		"ipv6 as ipv4": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "A"
+			spec.Route53Records.ResourceRecords = []string{"2001:db8::1"}
+		}, false},
+		"ipv6": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "AAAA"
+			spec.Route53Records.ResourceRecords = []string{"2001:db8::1"}
+		}, true},
+		"mx": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "MX"
+			spec.Route53Records.ResourceRecords = []string{"10 mail.example.com"}
+		}, true},
+		"mx without priority": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "MX"
+			spec.Route53Records.ResourceRecords = []string{"mail.example.com"}
+		}, false},
+		"srv": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Name = "_sip._tcp.example.com"
+			spec.Route53Records.Type = "SRV"
+			spec.Route53Records.ResourceRecords = []string{"1 10 5060 sip.example.com"}
+		}, true},
+		"srv port out of range": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "SRV"
+			spec.Route53Records.ResourceRecords = []string{"1 10 70000 sip.example.com"}
+		}, false},
+		"caa": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "CAA"
+			spec.Route53Records.ResourceRecords = []string{`0 issue "letsencrypt.org"`}
+		}, true},
+		"typed mx": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "MX"
+			spec.Route53Records.ResourceRecords = nil
+			spec.Route53Records.MX = []netv1alpha1.MXValue{{Priority: 10, Host: "mail.example.com"}}
+		}, true},
+		"typed mx and resourceRecords": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "MX"
+			spec.Route53Records.ResourceRecords = []string{"10 mail.example.com"}
+			spec.Route53Records.MX = []netv1alpha1.MXValue{{Priority: 10, Host: "mail.example.com"}}
+		}, false},
+		"typed value of another type": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "TXT"
+			spec.Route53Records.ResourceRecords = nil
+			spec.Route53Records.MX = []netv1alpha1.MXValue{{Priority: 10, Host: "mail.example.com"}}
+		}, false},
+		"typed spf": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "SPF"
+			spec.Route53Records.ResourceRecords = nil
+			spec.Route53Records.TXT = []string{"v=spf1 -all"}
+		}, true},
+		"typed srv port out of range": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "SRV"
+			spec.Route53Records.ResourceRecords = nil
+			spec.Route53Records.SRV = []netv1alpha1.SRVValue{{Priority: 1, Weight: 10, Port: 70000, Target: "sip.example.com"}}
+		}, false},
+		"typed caa bad tag": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "CAA"
+			spec.Route53Records.ResourceRecords = nil
+			spec.Route53Records.CAA = []netv1alpha1.CAAValue{{Tag: "is-sue", Value: "letsencrypt.org"}}
+		}, false},
+		"typed naptr": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.Route53Records.Type = "NAPTR"
+			spec.Route53Records.ResourceRecords = nil
+			spec.Route53Records.NAPTR = []netv1alpha1.NAPTRValue{{Order: 100, Preference: 10, Flags: "S", Service: "SIP+D2U", Replacement: "_sip._udp.example.com"}}
+		}, true},
+		"provider": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "route53"}
+		}, true},
+		"outside the provider zones": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "route53"}
+			spec.Route53Records.Name = "www.example.org"
+		}, false},
+		"missing provider": {func(spec *netv1alpha1.DnsRecordSpec) {
+			spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "missing"}
+		}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			record := newTestRecord()
+			test.change(&record.Spec)
+			err := validator.ValidateCreate(context.Background(), record)
+			if test.valid && err != nil {
+				t.Errorf("expected the record to be valid: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("expected the record to be rejected")
+			}
+		})
+	}
+}
+
+func TestDnsRecordValidatorAllowsUnchangedSpec(t *testing.T) {
+	validator := &DnsRecordWebhook{Client: newTestReconciler(newMemoryProvider()).Client}
+	old := newTestRecord()
+	old.Spec.Route53Records.ResourceRecords = []string{"a.example.com", "b.example.com"}
+	updated := old.DeepCopy()
+	updated.Finalizers = []string{dnsRecordFinalizer}
+
+	if err := validator.ValidateUpdate(context.Background(), old, updated); err != nil {
+		t.Errorf("the records created before the webhook must accept the finalizer: %v", err)
+	}
+	updated.Spec.Route53Records.Ttl = 60
+	if err := validator.ValidateUpdate(context.Background(), old, updated); err == nil {
+		t.Error("a spec change must be validated")
+	}
+}
+
+func TestDnsRecordWebhookDefaults(t *testing.T) {
+	provider := &netv1alpha1.DnsProvider{ObjectMeta: metav1.ObjectMeta{Name: "route53", Namespace: "default"}, Spec: newTestProviderSpec()}
+	webhook := &DnsRecordWebhook{Client: newTestReconciler(newMemoryProvider(), provider).Client}
+
+	record := newTestRecord()
+	record.Spec.Route53Records.Name = "WWW.Example.com"
+	record.Spec.Route53Records.Ttl = 0
+	record.Spec.Route53Records.AwsSecrets = netv1alpha1.AwsSecret{SecretName: "aws"}
+	if err := webhook.Default(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	spec := record.Spec.Route53Records
+	if spec.Name != "www.example.com." || spec.Ttl != netv1alpha1.DefaultTtl || spec.AwsSecrets.SecretNamespace != "default" || spec.Comment != "default/www" {
+		t.Errorf("unexpected defaults: %+v", spec)
+	}
+	defaulted := record.DeepCopy()
+	if err := webhook.Default(context.Background(), defaulted); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(defaulted.Spec, record.Spec) {
+		t.Errorf("defaulting must be idempotent: %+v", defaulted.Spec.Route53Records)
+	}
+
+	record = newTestRecord()
+	record.Spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "route53"}
+	record.Spec.Route53Records.Name = "www"
+	record.Spec.Route53Records.Ttl = 0
+	if err := webhook.Default(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	if spec := record.Spec.Route53Records; spec.Name != "www.example.com." || spec.Ttl != 60 {
+		t.Errorf("expected the name expanded against the zone and the ttl of the provider: %+v", spec)
+	}
+
+	record = newTestRecord()
+	record.Spec.ProviderRef = &netv1alpha1.DnsProviderRef{Name: "missing"}
+	record.Spec.Route53Records.Ttl = 0
+	if err := webhook.Default(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Spec.Route53Records.Ttl != 0 {
+		t.Errorf("the ttl of a record whose provider is missing must be left to the reconciler")
+	}
+}
+
+// hostedZoneResponse returns a GetHostedZone response
+func hostedZoneResponse(id, name string) string {
+	return `<GetHostedZoneResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` + hostedZoneXml(id, name, false) + `</GetHostedZoneResponse>`
+}
+
+func TestDnsRecordValidatorChecksHostedZone(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123"] = hostedZoneResponse("Z123", "example.com.")
+	provider := newTestRoute53Provider(fake)
+	validator := &DnsRecordWebhook{Client: provider.Client, Route53: provider}
+
+	record := newTestRoute53Record()
+	if err := validator.ValidateCreate(context.Background(), record); err != nil {
+		t.Errorf("expected the record to be valid: %v", err)
+	}
+	record.Spec.Route53Records.Name = "www.example.org"
+	if err := validator.ValidateCreate(context.Background(), record); err == nil {
+		t.Error("expected a name outside the hosted zone to be rejected")
+	}
+	if calls := len(fake.bodies("GET /2013-04-01/hostedzone/Z123")); calls != 1 {
+		t.Errorf("expected the hosted zone to be cached, got %d calls", calls)
+	}
+
+	// Route53 can't be reached with the credentials of the record: the reconciler checks the name
+	record.Spec.Route53Records.AwsSecrets.SecretName = "missing"
+	if err := validator.ValidateCreate(context.Background(), record); err != nil {
+		t.Errorf("the webhook must not fail without the hosted zone: %v", err)
+	}
+}
+
+func TestDnsRecordWebhookExpandsRelativeNames(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123"] = hostedZoneResponse("Z123", "example.com.")
+	provider := newTestRoute53Provider(fake)
+	webhook := &DnsRecordWebhook{Client: provider.Client, Route53: provider}
+
+	for name, expected := range map[string]string{
+		"www":              "www.example.com.",
+		"api.eu":           "api.eu.example.com.",
+		"www.example.com":  "www.example.com.",
+		"www.example.org.": "www.example.org.",
+	} {
+		record := newTestRoute53Record()
+		record.Spec.Route53Records.Name = name
+		if err := webhook.Default(context.Background(), record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Spec.Route53Records.Name != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, record.Spec.Route53Records.Name)
+		}
+	}
+
+	// The hosted zone can't be found: the relative name is rejected, not turned into a top-level domain
+	record := newTestRoute53Record()
+	record.Spec.Route53Records.Name = "www"
+	record.Spec.Route53Records.AwsSecrets.SecretName = "missing"
+	if err := webhook.Default(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Spec.Route53Records.Name != "www" {
+		t.Errorf("expected the name to be left relative, got %s", record.Spec.Route53Records.Name)
+	}
+	if err := webhook.ValidateCreate(context.Background(), record); err == nil {
+		t.Error("expected a relative name to be rejected")
+	}
+}
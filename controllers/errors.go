@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Reasons used to classify the provider errors
+const (
+	ReasonThrottled        = "Throttled"
+	ReasonServerError      = "ServerError"
+	ReasonNetworkError     = "NetworkError"
+	ReasonInvalidRecord    = "InvalidRecord"
+	ReasonInvalidRequest   = "InvalidRequest"
+	ReasonCredentialsError = "CredentialsError"
+)
+
+const (
+	// backoffBase delay before the first retry
+	backoffBase = 5 * time.Second
+	// backoffMax maximum delay between two retries
+	backoffMax = 10 * time.Minute
+)
+
+// ProviderError is an error returned by a dns provider, classified as retryable or terminal
+type ProviderError struct {
+	// Reason CamelCase code describing the error, used as condition reason
+	Reason string
+	// Retryable true if the same call may succeed later without changes to the record
+	Retryable bool
+	Err       error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// NewRetryableError wraps an error that may go away by itself, like throttling or a network failure
+func NewRetryableError(reason string, err error) error {
+	return &ProviderError{Reason: reason, Retryable: true, Err: err}
+}
+
+// NewTerminalError wraps an error that requires a change to the record or to its configuration
+func NewTerminalError(reason string, err error) error {
+	return &ProviderError{Reason: reason, Retryable: false, Err: err}
+}
+
+// ClassifyError returns the classification of err. Errors not classified by the provider
+// are classified using their http status code, and considered retryable when unknown
+func ClassifyError(err error) *ProviderError {
+	var providerError *ProviderError
+	if errors.As(err, &providerError) {
+		return providerError
+	}
+
+	var httpError interface{ HTTPStatusCode() int }
+	if errors.As(err, &httpError) {
+		switch code := httpError.HTTPStatusCode(); {
+		case code == 429:
+			return &ProviderError{Reason: ReasonThrottled, Retryable: true, Err: err}
+		case code >= 500:
+			return &ProviderError{Reason: ReasonServerError, Retryable: true, Err: err}
+		case code == 401 || code == 403:
+			return &ProviderError{Reason: ReasonCredentialsError, Retryable: false, Err: err}
+		case code >= 400:
+			return &ProviderError{Reason: ReasonInvalidRequest, Retryable: false, Err: err}
+		}
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return &ProviderError{Reason: ReasonNetworkError, Retryable: true, Err: err}
+	}
+
+	return &ProviderError{Reason: ReasonProviderError, Retryable: true, Err: err}
+}
+
+// backoff returns the delay before the next retry, after the given number of consecutive failures.
+// The delay doubles at each failure up to backoffMax; half of it is random to spread the retries
+func backoff(failures int32) time.Duration {
+	delay := backoffMax
+	if failures < 1 {
+		failures = 1
+	}
+	if failures <= 20 {
+		delay = backoffBase << (failures - 1)
+	}
+	if delay > backoffMax {
+		delay = backoffMax
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
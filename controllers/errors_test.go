@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"net"
+	"testing"
+	"time"
+)
+
+type httpStatusError int
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("http status %d", int(e))
+}
+
+func (e httpStatusError) HTTPStatusCode() int {
+	return int(e)
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err       error
+		reason    string
+		retryable bool
+	}{
+		{httpStatusError(429), ReasonThrottled, true},
+		{httpStatusError(503), ReasonServerError, true},
+		{httpStatusError(403), ReasonCredentialsError, false},
+		{httpStatusError(400), ReasonInvalidRequest, false},
+		{fmt.Errorf("wrapped: %w", httpStatusError(500)), ReasonServerError, true},
+		{&net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}, ReasonNetworkError, true},
+		{NewTerminalError(ReasonInvalidRecord, fmt.Errorf("bad record")), ReasonInvalidRecord, false},
+		{fmt.Errorf("unknown"), ReasonProviderError, true},
+	}
+
+	for _, c := range cases {
+		classified := ClassifyError(c.err)
+		if classified.Reason != c.reason || classified.Retryable != c.retryable {
+			t.Errorf("%v: expected %s/%v, got %s/%v", c.err, c.reason, c.retryable, classified.Reason, classified.Retryable)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for failures, expected := range map[int32]time.Duration{1: backoffBase, 2: 2 * backoffBase, 4: 8 * backoffBase, 30: backoffMax} {
+		for i := 0; i < 20; i++ {
+			if delay := backoff(failures); delay < expected/2 || delay > expected {
+				t.Errorf("backoff(%d) = %s, expected between %s and %s", failures, delay, expected/2, expected)
+			}
+		}
+	}
+}
+
+func TestReconcileRetriesRetryableErrors(t *testing.T) {
+	memory := newMemoryProvider()
+	memory.err = NewRetryableError(ReasonThrottled, fmt.Errorf("slow down"))
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+
+	result, updated := reconcileRecord(t, r, record)
+	if result.RequeueAfter == 0 || updated.Status.Failures != 1 {
+		t.Errorf("expected a retry, got %+v with %d failures", result, updated.Status.Failures)
+	}
+	if synced := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionSynced); synced.Reason != ReasonThrottled {
+		t.Errorf("unexpected reason %s", synced.Reason)
+	}
+
+	memory.err = nil
+	_, updated = reconcileRecord(t, r, record)
+	if updated.Status.Failures != 0 {
+		t.Error("failures must be reset after a successful apply")
+	}
+}
+
+func TestReconcileDoesNotRetryTerminalErrors(t *testing.T) {
+	memory := newMemoryProvider()
+	memory.err = NewTerminalError(ReasonInvalidRecord, fmt.Errorf("bad record"))
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+
+	result, updated := reconcileRecord(t, r, record)
+	if result.RequeueAfter != 0 || result.Requeue {
+		t.Errorf("terminal errors must not be retried: %+v", result)
+	}
+	if synced := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionSynced); synced.Reason != ReasonInvalidRecord {
+		t.Errorf("unexpected reason %s", synced.Reason)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// dnsAnnotation opts an Ingress in: "true" creates a DnsRecord for each of its hosts
+const dnsAnnotation = "net.beekube.cloud/dns"
+
+// IngressReconciler creates a DnsRecord for each host of the annotated Ingresses,
+// pointing to the address of their load balancer
+type IngressReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch
+
+func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	ingress := &networkingv1.Ingress{}
+	if err := r.Get(ctx, req.NamespacedName, ingress); err != nil {
+		if errors.IsNotFound(err) {
+			// The DnsRecords are garbage collected
+			return DoNotRequeue()
+		}
+		return RequeueWithError(err)
+	}
+	if ingress.GetDeletionTimestamp() != nil {
+		return DoNotRequeue()
+	}
+
+	// The records of an Ingress that is no longer annotated are deleted
+	if ingress.Annotations[dnsAnnotation] != "true" {
+		return RequeueWithError(syncSourceRecords(ctx, r.Client, r.Scheme, ingress, nil))
+	}
+
+	options, err := sourceOptionsOf(ingress)
+	if err != nil {
+		logger.Error(err, "invalid annotations, the DnsRecords are not updated")
+		return DoNotRequeue()
+	}
+
+	// The records are left alone until the load balancer has an address
+	addresses := ingress.Status.LoadBalancer.Ingress
+	if len(addresses) == 0 {
+		logger.Info("waiting for the address of the load balancer")
+		return DoNotRequeue()
+	}
+
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+	records := sourceRecords("Ingress", ingress, hosts, addresses, options)
+	return RequeueWithError(syncSourceRecords(ctx, r.Client, r.Scheme, ingress, records))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Only the changes to the spec of the owned DnsRecords matter, not their status
+func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&networkingv1.Ingress{}).
+		Owns(&netv1alpha1.DnsRecord{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
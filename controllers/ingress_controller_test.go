@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+	"testing"
+)
+
+func newTestIngress(hosts ...string) *networkingv1.Ingress {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "www",
+			Namespace: "default",
+			UID:       "ingress-uid",
+			Annotations: map[string]string{
+				dnsAnnotation:         "true",
+				dnsProviderAnnotation: "route53",
+				dnsTtlAnnotation:      "60",
+			},
+		},
+		Status: networkingv1.IngressStatus{LoadBalancer: v1.LoadBalancerStatus{
+			Ingress: []v1.LoadBalancerIngress{{IP: "10.0.0.2"}, {IP: "10.0.0.1"}},
+		}},
+	}
+	for _, host := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{Host: host})
+	}
+	return ingress
+}
+
+// reconcileSource reconciles the source of the generated records, and returns the DnsRecords by name
+func reconcileSource(t *testing.T, r interface {
+	reconcile.Reconciler
+	client.Reader
+}, source client.Object) map[string]netv1alpha1.DnsRecord {
+	t.Helper()
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(source)}); err != nil {
+		t.Fatal(err)
+	}
+	records := &netv1alpha1.DnsRecordList{}
+	if err := r.List(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]netv1alpha1.DnsRecord{}
+	for _, record := range records.Items {
+		byName[record.Name] = record
+	}
+	return byName
+}
+
+func TestIngressCreatesRecordsForHosts(t *testing.T) {
+	ingress := newTestIngress("www.example.com", "API.example.com", "")
+	dnsReconciler := newTestReconciler(newMemoryProvider(), ingress)
+	r := &IngressReconciler{Client: dnsReconciler.Client, Scheme: dnsReconciler.Scheme}
+
+	records := reconcileSource(t, r, ingress)
+	if len(records) != 2 {
+		t.Fatalf("expected a record for each host, got %v", records)
+	}
+	record, found := records["www-api.example.com"]
+	if !found {
+		t.Fatalf("record of api.example.com not found: %v", records)
+	}
+	spec := record.Spec.Route53Records
+	if spec.Name != "api.example.com." || spec.Type != "A" || spec.Ttl != 60 || !reflect.DeepEqual(spec.ResourceRecords, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("unexpected record %+v", spec)
+	}
+	if ref := record.Spec.ProviderRef; ref == nil || ref.Name != "route53" || ref.Kind != netv1alpha1.DnsProviderKind {
+		t.Errorf("expected the provider of the annotation, got %v", ref)
+	}
+	if !metav1.IsControlledBy(&record, ingress) {
+		t.Errorf("the record must be owned by the Ingress: %v", record.OwnerReferences)
+	}
+}
+
+func TestIngressUpdatesRecords(t *testing.T) {
+	ingress := newTestIngress("www.example.com", "api.example.com")
+	dnsReconciler := newTestReconciler(newMemoryProvider(), ingress)
+	r := &IngressReconciler{Client: dnsReconciler.Client, Scheme: dnsReconciler.Scheme}
+	reconcileSource(t, r, ingress)
+
+	// The load balancer moves to a hostname, and a host is removed
+	ingress.Spec.Rules = ingress.Spec.Rules[:1]
+	ingress.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{Hostname: "lb.elb.amazonaws.com"}, {IP: "2001:db8::1"}}
+	if err := r.Update(context.Background(), ingress); err != nil {
+		t.Fatal(err)
+	}
+	records := reconcileSource(t, r, ingress)
+	if len(records) != 1 {
+		t.Fatalf("expected only a CNAME record, got %v", records)
+	}
+	if spec := records["www-www.example.com"].Spec.Route53Records; spec.Type != "CNAME" || !reflect.DeepEqual(spec.ResourceRecords, []string{"lb.elb.amazonaws.com"}) {
+		t.Errorf("expected a CNAME to the load balancer, got %+v", spec)
+	}
+
+	// The records of an Ingress no longer annotated are deleted
+	delete(ingress.Annotations, dnsAnnotation)
+	if err := r.Update(context.Background(), ingress); err != nil {
+		t.Fatal(err)
+	}
+	if records := reconcileSource(t, r, ingress); len(records) != 0 {
+		t.Errorf("expected no records, got %v", records)
+	}
+}
+
+func TestSourceRecordsDoNotMixCnameAndAaaa(t *testing.T) {
+	ingress := newTestIngress()
+	addresses := []v1.LoadBalancerIngress{{Hostname: "lb.elb.amazonaws.com"}, {IP: "2001:db8::1"}}
+
+	records := sourceRecords("Ingress", ingress, []string{"www.example.com"}, addresses, sourceOptions{})
+	if len(records) != 1 || records[0].Spec.Route53Records.Type != "CNAME" {
+		t.Errorf("expected only a CNAME record, got %+v", records)
+	}
+
+	// An alias is an A record: the AAAA record can share its name
+	records = sourceRecords("Ingress", ingress, []string{"www.example.com"}, addresses, sourceOptions{AliasHostedZoneId: "ZLB"})
+	if len(records) != 2 || records[0].Spec.Route53Records.Alias == nil || records[1].Spec.Route53Records.Type != "AAAA" {
+		t.Errorf("expected an alias and an AAAA record, got %+v", records)
+	}
+}
+
+func TestIngressDoesNotUpdateDefaultedRecords(t *testing.T) {
+	ingress := newTestIngress("www.example.com")
+	delete(ingress.Annotations, dnsTtlAnnotation)
+	delete(ingress.Annotations, dnsProviderAnnotation)
+	dnsReconciler := newTestReconciler(newMemoryProvider(), ingress)
+	r := &IngressReconciler{Client: dnsReconciler.Client, Scheme: dnsReconciler.Scheme}
+	record := reconcileSource(t, r, ingress)["www-www.example.com"]
+
+	// The API server stores the record with the defaults of the webhook and of the CRD
+	record.Spec.Route53Records.Ttl = netv1alpha1.DefaultTtl
+	record.Spec.DriftPolicy = netv1alpha1.DriftPolicyCorrect
+	record.Spec.AdoptPolicy = netv1alpha1.AdoptPolicyNever
+	if err := r.Update(context.Background(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if updated := reconcileSource(t, r, ingress)["www-www.example.com"]; updated.ResourceVersion != record.ResourceVersion {
+		t.Errorf("the defaulted record must not be updated: %+v", updated.Spec)
+	}
+}
+
+func TestIngressDoesNotTakeOverRecords(t *testing.T) {
+	ingress := newTestIngress("www.example.com")
+	existing := newTestRecord()
+	existing.Name = "www-www.example.com"
+	dnsReconciler := newTestReconciler(newMemoryProvider(), ingress, existing)
+	r := &IngressReconciler{Client: dnsReconciler.Client, Scheme: dnsReconciler.Scheme}
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ingress)}); err == nil {
+		t.Error("a DnsRecord not owned by the Ingress must not be changed")
+	}
+}
+
+func TestSourceOptions(t *testing.T) {
+	ingress := newTestIngress()
+	ingress.Annotations[dnsProviderAnnotation] = "ClusterDnsProvider/route53"
+	options, err := sourceOptionsOf(ingress)
+	if err != nil || options.ProviderRef.Kind != netv1alpha1.ClusterDnsProviderKind || options.ProviderRef.Name != "route53" || options.Ttl != 60 {
+		t.Errorf("unexpected options %+v, %v", options, err)
+	}
+
+	ingress.Annotations[dnsProviderAnnotation] = "Secret/route53"
+	if _, err := sourceOptionsOf(ingress); err == nil {
+		t.Error("an unknown provider kind must be rejected")
+	}
+	ingress.Annotations[dnsProviderAnnotation] = "route53"
+	ingress.Annotations[dnsTtlAnnotation] = "-1"
+	if _, err := sourceOptionsOf(ingress); err == nil {
+		t.Error("a negative ttl must be rejected")
+	}
+}
+
+func TestSourceRecordName(t *testing.T) {
+	if name := sourceRecordName("www", "*.example.com"); name != "www-wildcard.example.com" {
+		t.Errorf("unexpected name %s", name)
+	}
+	long := sourceRecordName("www", strings.Repeat("a.", 150)+"example.com")
+	if len(long) > 248 || long[:4] != "www-" {
+		t.Errorf("unexpected name %s", long)
+	}
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"strconv"
+)
+
+var (
+	// providerErrorsTotal counts the errors returned by the dns providers, by classification
+	providerErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dnsrecord_provider_errors_total",
+		Help: "Number of errors returned by the dns providers, by provider, reason and retryability",
+	}, []string{"provider", "reason", "retryable"})
+
+	// recordDrifted is 1 for the records whose live value differs from the spec
+	recordDrifted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dnsrecord_drifted",
+		Help: "1 if the live dns record differs from the DnsRecord spec, 0 otherwise",
+	}, []string{"namespace", "name"})
+
+	// driftDetectedTotal counts the drifts found by the periodic resync
+	driftDetectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dnsrecord_drift_detected_total",
+		Help: "Number of drifts between the live dns records and the DnsRecord specs, by drift policy",
+	}, []string{"policy"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(providerErrorsTotal, recordDrifted, driftDetectedTotal)
+}
+
+// recordProviderError counts a classified provider error
+func recordProviderError(provider Provider, providerError *ProviderError) {
+	providerErrorsTotal.WithLabelValues(provider.Name(), providerError.Reason, strconv.FormatBool(providerError.Retryable)).Inc()
+}
+
+// recordDrift updates the drift metrics of the record
+func recordDrift(crd *netv1alpha1.DnsRecord, drifted bool) {
+	value := 0.0
+	if drifted {
+		value = 1
+	}
+	recordDrifted.WithLabelValues(crd.Namespace, crd.Name).Set(value)
+}
+
+// forgetDrift removes the drift metrics of a deleted record
+func forgetDrift(crd *netv1alpha1.DnsRecord) {
+	recordDrifted.DeleteLabelValues(crd.Namespace, crd.Name)
+}
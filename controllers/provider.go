@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"sort"
+	"strings"
+)
+
+// Provider is a DNS backend able to manage the records described by a DnsRecord.
+// Implementations must be safe for concurrent use.
+type Provider interface {
+	// Name unique name of the provider
+	Name() string
+	// Handles returns true if this provider is responsible for the given record
+	Handles(record *netv1alpha1.DnsRecord) bool
+	// Apply creates or updates the dns record. It returns the id of the submitted change,
+	// or an empty string if the provider does not track changes.
+	// If the record has been renamed, the record last applied (Status.AppliedRecord) must be removed
+	Apply(ctx context.Context, record *netv1alpha1.DnsRecord) (string, error)
+	// Delete removes the dns record last applied. Deleting a record that does not exist is not an error
+	Delete(ctx context.Context, record *netv1alpha1.DnsRecord) error
+	// Current returns the record currently served by the dns, or nil if it does not exist
+	Current(ctx context.Context, record *netv1alpha1.DnsRecord) (*RecordSet, error)
+	// ChangeStatus returns true once the change returned by Apply is live on the dns servers
+	ChangeStatus(ctx context.Context, record *netv1alpha1.DnsRecord, changeId string) (bool, error)
+}
+
+// Resolver is implemented by the providers that complete the spec with discovered values,
+// like the Route53 hosted zone. Resolve changes the record in memory only
+type Resolver interface {
+	Resolve(ctx context.Context, record *netv1alpha1.DnsRecord) error
+}
+
+// RecordSet is a provider-agnostic view of a dns record
+type RecordSet struct {
+	Name   string
+	Type   string
+	Ttl    int64
+	Values []string
+	// Alias if set, the record is an alias and has no ttl nor values
+	Alias *netv1alpha1.Route53Alias
+	// Routing policy, the SetIdentifier tells apart the records with the same name and type
+	Routing netv1alpha1.Route53Routing
+}
+
+// ProviderRegistry holds the providers known to the operator.
+// Providers must be registered before the manager is started.
+type ProviderRegistry struct {
+	providers []Provider
+}
+
+func NewProviderRegistry(providers ...Provider) *ProviderRegistry {
+	registry := &ProviderRegistry{}
+	for _, p := range providers {
+		registry.Register(p)
+	}
+	return registry
+}
+
+// Register adds a provider to the registry, replacing any provider with the same name
+func (p *ProviderRegistry) Register(provider Provider) {
+	for i, existing := range p.providers {
+		if existing.Name() == provider.Name() {
+			p.providers[i] = provider
+			return
+		}
+	}
+	p.providers = append(p.providers, provider)
+}
+
+// Get returns the provider registered with the given name, or nil
+func (p *ProviderRegistry) Get(name string) Provider {
+	for _, provider := range p.providers {
+		if provider.Name() == name {
+			return provider
+		}
+	}
+	return nil
+}
+
+// For returns the first provider that handles the record, or nil if none does
+func (p *ProviderRegistry) For(record *netv1alpha1.DnsRecord) Provider {
+	for _, provider := range p.providers {
+		if provider.Handles(record) {
+			return provider
+		}
+	}
+	return nil
+}
+
+// normalizeDnsName returns the lowercase name without the trailing dot
+func normalizeDnsName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// desiredRecordSet returns the record described by the spec, with the defaults applied
+func desiredRecordSet(record *netv1alpha1.DnsRecord) RecordSet {
+	return recordSetOf(record.Spec.Route53Records)
+}
+
+// recordSetOf returns the record set described by a Route53Record, with the defaults applied
+func recordSetOf(spec netv1alpha1.Route53Record) RecordSet {
+	if spec.Alias != nil {
+		return RecordSet{Name: spec.Name, Type: spec.Type, Alias: spec.Alias, Routing: spec.Route53Routing}
+	}
+	ttl := spec.Ttl
+	if ttl == 0 {
+		ttl = netv1alpha1.DefaultTtl
+	}
+	values := spec.ResourceRecords
+	if len(values) == 0 {
+		values = route53Values(spec.StructuredValues)
+	}
+	return RecordSet{Name: spec.Name, Type: spec.Type, Ttl: ttl, Values: values, Routing: spec.Route53Routing}
+}
+
+// appliedRecord returns the spec with the zone, name, type and values last applied to the provider.
+// They differ from the spec if the record has been renamed after the last apply
+func appliedRecord(record *netv1alpha1.DnsRecord) netv1alpha1.Route53Record {
+	spec := record.Spec.Route53Records
+	applied := record.Status.AppliedRecord
+	if applied == nil {
+		return spec
+	}
+	spec.ZoneId = applied.ZoneId
+	spec.Name = applied.Name
+	spec.Type = applied.Type
+	spec.Ttl = applied.Ttl
+	// The applied values are already rendered
+	spec.ResourceRecords = applied.ResourceRecords
+	spec.StructuredValues = netv1alpha1.StructuredValues{}
+	spec.Alias = applied.Alias
+	spec.Route53Routing = applied.Route53Routing
+	return spec
+}
+
+// isRenamed returns true if the zone, name, type or set identifier of the spec differ from the record last applied
+func isRenamed(record *netv1alpha1.DnsRecord) bool {
+	applied := record.Status.AppliedRecord
+	spec := record.Spec.Route53Records
+	return applied != nil &&
+		(applied.ZoneId != spec.ZoneId ||
+			normalizeDnsName(applied.Name) != normalizeDnsName(spec.Name) ||
+			applied.Type != spec.Type ||
+			applied.SetIdentifier != spec.SetIdentifier)
+}
+
+// diffRecordSets returns a human readable description of the differences between
+// the desired and the current record, or an empty string if they match
+func diffRecordSets(desired RecordSet, current *RecordSet) string {
+	if current == nil {
+		return fmt.Sprintf("record %s %s not found", desired.Type, desired.Name)
+	}
+
+	if desired.Alias != nil || current.Alias != nil {
+		if desired.Alias == nil || current.Alias == nil ||
+			normalizeDnsName(desired.Alias.DNSName) != normalizeDnsName(current.Alias.DNSName) ||
+			desired.Alias.HostedZoneId != current.Alias.HostedZoneId ||
+			desired.Alias.EvaluateTargetHealth != current.Alias.EvaluateTargetHealth {
+			return fmt.Sprintf("alias %s != %s", describeAlias(current), describeAlias(&desired))
+		}
+		if routing, currentRouting := describeRouting(desired.Routing), describeRouting(current.Routing); routing != currentRouting {
+			return fmt.Sprintf("routing %s != %s", currentRouting, routing)
+		}
+		return ""
+	}
+
+	var diffs []string
+	if routing, currentRouting := describeRouting(desired.Routing), describeRouting(current.Routing); routing != currentRouting {
+		diffs = append(diffs, fmt.Sprintf("routing %s != %s", currentRouting, routing))
+	}
+	if desired.Ttl != current.Ttl {
+		diffs = append(diffs, fmt.Sprintf("ttl %d != %d", current.Ttl, desired.Ttl))
+	}
+	currentValues := normalizeValues(current.Type, current.Values)
+	desiredValues := normalizeValues(desired.Type, desired.Values)
+	if strings.Join(currentValues, ",") != strings.Join(desiredValues, ",") {
+		diffs = append(diffs, fmt.Sprintf("values [%s] != [%s]", strings.Join(currentValues, ","), strings.Join(desiredValues, ",")))
+	}
+
+	return strings.Join(diffs, "; ")
+}
+
+// describeAlias returns the alias target of the record, or its values if it is not an alias
+func describeAlias(set *RecordSet) string {
+	if set.Alias == nil {
+		return "[" + strings.Join(set.Values, ",") + "]"
+	}
+	return fmt.Sprintf("%s/%s (evaluate health: %v)", set.Alias.HostedZoneId, set.Alias.DNSName, set.Alias.EvaluateTargetHealth)
+}
+
+// describeRouting returns the routing policy as json, an empty object for simple records
+func describeRouting(routing netv1alpha1.Route53Routing) string {
+	data, _ := json.Marshal(routing)
+	return string(data)
+}
+
+// normalizeValues sorts the values; values that are hostnames are compared without the trailing dot
+func normalizeValues(recordType string, values []string) []string {
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		switch recordType {
+		case "CNAME", "NS", "PTR":
+			value = normalizeDnsName(value)
+		}
+		normalized = append(normalized, value)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
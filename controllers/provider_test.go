@@ -0,0 +1,556 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryProvider keeps the records in a map, keyed by name and type
+type memoryProvider struct {
+	sync.Mutex
+	records map[string]RecordSet
+	// err if set, is returned by every call
+	err error
+	// changes number of changes applied
+	changes int
+	// pending if true, changes never propagate
+	pending bool
+	// changeErr if set, is returned by ChangeStatus
+	changeErr error
+}
+
+func newMemoryProvider() *memoryProvider {
+	return &memoryProvider{records: map[string]RecordSet{}}
+}
+
+func memoryKey(record *netv1alpha1.DnsRecord) string {
+	return recordKey(record.Spec.Route53Records)
+}
+
+func recordKey(record netv1alpha1.Route53Record) string {
+	if record.SetIdentifier != "" {
+		return fmt.Sprintf("%s/%s/%s", normalizeDnsName(record.Name), record.Type, record.SetIdentifier)
+	}
+	return fmt.Sprintf("%s/%s", normalizeDnsName(record.Name), record.Type)
+}
+
+func (m *memoryProvider) Name() string {
+	return "memory"
+}
+
+func (m *memoryProvider) Handles(record *netv1alpha1.DnsRecord) bool {
+	return record.Spec.Route53Records.Name != ""
+}
+
+func (m *memoryProvider) Apply(_ context.Context, record *netv1alpha1.DnsRecord) (string, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.err != nil {
+		return "", m.err
+	}
+	spec := record.Spec.Route53Records
+	if isRenamed(record) {
+		delete(m.records, recordKey(appliedRecord(record)))
+	}
+	m.records[memoryKey(record)] = recordSetOf(spec)
+	m.changes++
+	return fmt.Sprintf("change-%d", m.changes), nil
+}
+
+func (m *memoryProvider) Delete(_ context.Context, record *netv1alpha1.DnsRecord) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.records, recordKey(appliedRecord(record)))
+	return nil
+}
+
+func (m *memoryProvider) Current(_ context.Context, record *netv1alpha1.DnsRecord) (*RecordSet, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	current, found := m.records[memoryKey(record)]
+	if !found {
+		return nil, nil
+	}
+	return &current, nil
+}
+
+func (m *memoryProvider) ChangeStatus(_ context.Context, _ *netv1alpha1.DnsRecord, _ string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.changeErr != nil {
+		return false, m.changeErr
+	}
+	return !m.pending, m.err
+}
+
+func newTestReconciler(provider Provider, objs ...client.Object) *DnsRecordReconciler {
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = netv1alpha1.AddToScheme(scheme)
+
+	return &DnsRecordReconciler{
+		Client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme:    scheme,
+		Providers: NewProviderRegistry(provider),
+	}
+}
+
+func newTestRecord() *netv1alpha1.DnsRecord {
+	return &netv1alpha1.DnsRecord{
+		ObjectMeta: metav1.ObjectMeta{Name: "www", Namespace: "default"},
+		Spec: netv1alpha1.DnsRecordSpec{
+			Route53Records: netv1alpha1.Route53Record{
+				Name:            "www.example.com",
+				Type:            "CNAME",
+				ZoneId:          "Z123",
+				ResourceRecords: []string{"lb.example.com"},
+				Ttl:             300,
+			},
+		},
+	}
+}
+
+func reconcileRecord(t *testing.T, r *DnsRecordReconciler, record *netv1alpha1.DnsRecord) (ctrl.Result, *netv1alpha1.DnsRecord) {
+	t.Helper()
+	key := types.NamespacedName{Namespace: record.Namespace, Name: record.Name}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+
+	updated := &netv1alpha1.DnsRecord{}
+	if err := r.Get(context.Background(), key, updated); err != nil {
+		return result, nil
+	}
+	return result, updated
+}
+
+// expireResync moves the last sync in the past, so that the next reconciliation checks the live record
+func expireResync(t *testing.T, r *DnsRecordReconciler, record *netv1alpha1.DnsRecord) {
+	t.Helper()
+	updated := &netv1alpha1.DnsRecord{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: record.Namespace, Name: record.Name}, updated); err != nil {
+		t.Fatal(err)
+	}
+	past := metav1.NewTime(time.Now().Add(-24 * time.Hour))
+	updated.Status.LastSyncTime = &past
+	if err := r.Status().Update(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProviderRegistry(t *testing.T) {
+	memory := newMemoryProvider()
+	registry := NewProviderRegistry(memory)
+
+	if registry.Get("memory") != memory {
+		t.Error("expected the memory provider to be registered")
+	}
+	if registry.Get("route53") != nil {
+		t.Error("unexpected route53 provider")
+	}
+	if registry.For(newTestRecord()) != memory {
+		t.Error("expected the memory provider to handle the record")
+	}
+	if registry.For(&netv1alpha1.DnsRecord{}) != nil {
+		t.Error("no provider should handle an empty record")
+	}
+
+	replacement := newMemoryProvider()
+	registry.Register(replacement)
+	if registry.Get("memory") != replacement {
+		t.Error("expected the provider to be replaced")
+	}
+}
+
+func TestReconcileAppliesRecord(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+
+	_, updated := reconcileRecord(t, r, record)
+
+	current, _ := memory.Current(context.Background(), record)
+	if current == nil || current.Values[0] != "lb.example.com" {
+		t.Errorf("record not applied: %+v", current)
+	}
+	if !controllerutil.ContainsFinalizer(updated, dnsRecordFinalizer) {
+		t.Error("finalizer not added")
+	}
+	if updated.Status.ChangeId != "change-1" || updated.Status.Status != StatusSynced || updated.Status.LastSyncTime == nil {
+		t.Errorf("unexpected status: %+v", updated.Status)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, netv1alpha1.ConditionReady) {
+		t.Errorf("record should be ready: %+v", updated.Status.Conditions)
+	}
+}
+
+// failingPatchClient fails the patches while fail is true
+type failingPatchClient struct {
+	client.Client
+	fail bool
+}
+
+func (c *failingPatchClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if c.fail {
+		return fmt.Errorf("patch refused")
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func TestReconcileAddsFinalizerToAppliedRecord(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+	patches := &failingPatchClient{Client: r.Client, fail: true}
+	r.Client = patches
+
+	key := types.NamespacedName{Namespace: record.Namespace, Name: record.Name}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err == nil {
+		t.Error("a failed finalizer patch must requeue the request")
+	}
+
+	// The spec has been applied: the next reconciliation adds the finalizer without applying it again
+	patches.fail = false
+	_, updated := reconcileRecord(t, r, record)
+	if !controllerutil.ContainsFinalizer(updated, dnsRecordFinalizer) {
+		t.Error("finalizer not added")
+	}
+	if memory.changes != 1 {
+		t.Errorf("the record must not be applied again, got %d changes", memory.changes)
+	}
+}
+
+func TestReconcileWaitsForPropagation(t *testing.T) {
+	memory := newMemoryProvider()
+	memory.pending = true
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+
+	result, updated := reconcileRecord(t, r, record)
+	if result.RequeueAfter == 0 {
+		t.Error("expected a requeue while the change is pending")
+	}
+	if !meta.IsStatusConditionFalse(updated.Status.Conditions, netv1alpha1.ConditionPropagated) {
+		t.Errorf("change should be pending: %+v", updated.Status.Conditions)
+	}
+
+	// Still pending: poll without applying the change again
+	result, _ = reconcileRecord(t, r, record)
+	if result.RequeueAfter == 0 || memory.changes != 1 {
+		t.Errorf("expected a requeue without a new change, got %d changes", memory.changes)
+	}
+
+	memory.pending = false
+	result, updated = reconcileRecord(t, r, record)
+	if result.RequeueAfter != 0 || memory.changes != 1 {
+		t.Errorf("unexpected result %+v with %d changes", result, memory.changes)
+	}
+	propagated := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionPropagated)
+	if propagated == nil || propagated.Status != metav1.ConditionTrue || propagated.Reason != ReasonChangeInSync {
+		t.Errorf("change should be propagated: %+v", propagated)
+	}
+}
+
+func TestReconcileStopsPollingOnTerminalChangeErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status metav1.ConditionStatus
+	}{
+		{NewTerminalError(ReasonChangeNotFound, fmt.Errorf("change not found")), metav1.ConditionUnknown},
+		{NewTerminalError(ReasonCredentialsError, fmt.Errorf("secret not found")), metav1.ConditionFalse},
+	}
+
+	for _, c := range cases {
+		memory := newMemoryProvider()
+		memory.pending = true
+		record := newTestRecord()
+		r := newTestReconciler(memory, record)
+		reconcileRecord(t, r, record)
+
+		memory.changeErr = c.err
+		result, updated := reconcileRecord(t, r, record)
+		if result.RequeueAfter != 0 {
+			t.Errorf("%s: the polling must stop, got %+v", c.err, result)
+		}
+		propagated := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionPropagated)
+		if propagated == nil || propagated.Status != c.status || propagated.Reason != ClassifyError(c.err).Reason {
+			t.Errorf("%s: unexpected Propagated condition %+v", c.err, propagated)
+		}
+		if isChangePending(updated) {
+			t.Errorf("%s: the change must no longer be pending", c.err)
+		}
+	}
+}
+
+func TestReconcileBacksOffOnRetryableChangeErrors(t *testing.T) {
+	memory := newMemoryProvider()
+	memory.pending = true
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+	reconcileRecord(t, r, record)
+
+	memory.changeErr = NewRetryableError(ReasonThrottled, fmt.Errorf("rate exceeded"))
+	reconcileRecord(t, r, record)
+	result, updated := reconcileRecord(t, r, record)
+	if updated.Status.Failures != 2 || result.RequeueAfter < backoffBase || result.RequeueAfter > 2*backoffBase {
+		t.Errorf("expected the second backoff, got %v after %d failures", result.RequeueAfter, updated.Status.Failures)
+	}
+	if !isChangePending(updated) {
+		t.Error("the change must still be pending")
+	}
+
+	memory.changeErr = nil
+	memory.pending = false
+	_, updated = reconcileRecord(t, r, record)
+	if updated.Status.Failures != 0 || !meta.IsStatusConditionTrue(updated.Status.Conditions, netv1alpha1.ConditionPropagated) {
+		t.Errorf("expected the change propagated, got %+v", updated.Status)
+	}
+}
+
+func TestReconcileReportsProviderError(t *testing.T) {
+	memory := newMemoryProvider()
+	memory.err = fmt.Errorf("boom")
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+
+	_, updated := reconcileRecord(t, r, record)
+
+	synced := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionSynced)
+	if synced == nil || synced.Status != metav1.ConditionFalse || synced.Reason != ReasonProviderError || synced.Message != "boom" {
+		t.Errorf("unexpected Synced condition: %+v", synced)
+	}
+	if updated.Status.Status != StatusError {
+		t.Errorf("unexpected status %s", updated.Status.Status)
+	}
+}
+
+func TestReconcileDeletesRecord(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+	_, updated := reconcileRecord(t, r, record)
+
+	if err := r.Delete(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	_, deleted := reconcileRecord(t, r, record)
+
+	if current, _ := memory.Current(context.Background(), record); current != nil {
+		t.Errorf("record not deleted: %+v", current)
+	}
+	if deleted != nil {
+		t.Error("the DnsRecord should be gone once the finalizer is removed")
+	}
+}
+
+func TestReconcileKeepsFinalizerOnProviderError(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+	_, updated := reconcileRecord(t, r, record)
+
+	memory.err = fmt.Errorf("boom")
+	if err := r.Delete(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	result, deleted := reconcileRecord(t, r, record)
+
+	if deleted == nil || !controllerutil.ContainsFinalizer(deleted, dnsRecordFinalizer) {
+		t.Error("finalizer must be kept when the provider fails")
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("expected a requeue")
+	}
+}
+
+func TestReconcileCorrectsDrift(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+	r.ResyncPeriod = time.Minute
+	// apply, then propagate
+	reconcileRecord(t, r, record)
+	reconcileRecord(t, r, record)
+
+	// Somebody edits the record in the console
+	memory.records[memoryKey(record)] = RecordSet{Name: "www.example.com", Type: "CNAME", Ttl: 300, Values: []string{"evil.example.com"}}
+	expireResync(t, r, record)
+
+	result, updated := reconcileRecord(t, r, record)
+	if current, _ := memory.Current(context.Background(), record); current.Values[0] != "lb.example.com" {
+		t.Errorf("drift not corrected: %+v", current)
+	}
+	drifted := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionDrifted)
+	if drifted == nil || drifted.Status != metav1.ConditionFalse || drifted.Reason != ReasonDriftCorrected {
+		t.Errorf("unexpected Drifted condition: %+v", drifted)
+	}
+	if result.RequeueAfter != propagationPollInterval {
+		t.Errorf("expected to wait for the correction to propagate, got %+v", result)
+	}
+
+	// Nothing changed: no new change is submitted
+	changes := memory.changes
+	reconcileRecord(t, r, record)
+	expireResync(t, r, record)
+	result, _ = reconcileRecord(t, r, record)
+	if memory.changes != changes {
+		t.Error("a record in sync must not be applied again")
+	}
+	if result.RequeueAfter != time.Minute {
+		t.Errorf("expected a resync, got %+v", result)
+	}
+
+	// Resync not due: the provider is not called at all
+	memory.err = fmt.Errorf("the provider must not be called")
+	result, _ = reconcileRecord(t, r, record)
+	if result.RequeueAfter <= 0 || result.RequeueAfter > time.Minute {
+		t.Errorf("expected to wait for the next resync, got %+v", result)
+	}
+}
+
+func TestReconcileReportsDrift(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	record.Spec.DriftPolicy = netv1alpha1.DriftPolicyReport
+	r := newTestReconciler(memory, record)
+	reconcileRecord(t, r, record)
+	reconcileRecord(t, r, record)
+
+	delete(memory.records, memoryKey(record))
+	r.ResyncPeriod = time.Minute
+	expireResync(t, r, record)
+
+	_, updated := reconcileRecord(t, r, record)
+	if current, _ := memory.Current(context.Background(), record); current != nil {
+		t.Errorf("drift must only be reported: %+v", current)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, netv1alpha1.ConditionDrifted) {
+		t.Errorf("drift not reported: %+v", updated.Status.Conditions)
+	}
+
+	// The record is restored by hand: the record is ready again
+	memory.records[memoryKey(record)] = recordSetOf(record.Spec.Route53Records)
+	expireResync(t, r, record)
+	_, updated = reconcileRecord(t, r, record)
+	if meta.IsStatusConditionTrue(updated.Status.Conditions, netv1alpha1.ConditionDrifted) ||
+		!meta.IsStatusConditionTrue(updated.Status.Conditions, netv1alpha1.ConditionReady) {
+		t.Errorf("record in sync not reported as ready: %+v", updated.Status.Conditions)
+	}
+}
+
+func TestDiffRecordSets(t *testing.T) {
+	desired := RecordSet{Name: "www.example.com", Type: "CNAME", Ttl: 300, Values: []string{"lb.example.com"}}
+	if diff := diffRecordSets(desired, &RecordSet{Name: "www.example.com.", Type: "CNAME", Ttl: 300, Values: []string{"LB.example.com."}}); diff != "" {
+		t.Errorf("unexpected diff %s", diff)
+	}
+	if diff := diffRecordSets(desired, &RecordSet{Name: "www.example.com.", Type: "CNAME", Ttl: 60, Values: []string{"lb.example.com"}}); diff == "" {
+		t.Error("ttl drift not detected")
+	}
+	if diff := diffRecordSets(desired, nil); diff == "" {
+		t.Error("missing record not detected")
+	}
+
+	alias := RecordSet{Name: "example.com", Type: "A", Alias: &netv1alpha1.Route53Alias{DNSName: "lb-1.eu-west-1.elb.amazonaws.com", HostedZoneId: "Z32O12XQLNTSW2"}}
+	if diff := diffRecordSets(alias, &RecordSet{Name: "example.com.", Type: "A", Alias: &netv1alpha1.Route53Alias{DNSName: "LB-1.eu-west-1.elb.amazonaws.com.", HostedZoneId: "Z32O12XQLNTSW2"}}); diff != "" {
+		t.Errorf("unexpected alias diff %s", diff)
+	}
+	if diff := diffRecordSets(alias, &RecordSet{Name: "example.com.", Type: "A", Ttl: 300, Values: []string{"10.0.0.1"}}); diff == "" {
+		t.Error("alias replaced by a plain record not detected")
+	}
+}
+
+func TestReconcileSkipsUnchangedSpec(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+	reconcileRecord(t, r, record)
+	reconcileRecord(t, r, record)
+
+	// Changes that don't affect the dns don't call the provider
+	_, updated := reconcileRecord(t, r, record)
+	updated.Spec.DriftPolicy = netv1alpha1.DriftPolicyReport
+	updated.Generation = 2
+	if err := r.Update(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	memory.err = fmt.Errorf("the provider must not be called")
+	_, updated = reconcileRecord(t, r, record)
+	if updated.Status.ObservedGeneration != 2 {
+		t.Errorf("observed generation not updated: %d", updated.Status.ObservedGeneration)
+	}
+
+	// A new ttl is applied
+	memory.err = nil
+	updated.Spec.Route53Records.Ttl = 60
+	updated.Generation = 3
+	if err := r.Update(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	_, updated = reconcileRecord(t, r, record)
+	if memory.changes != 2 || updated.Status.AppliedHash != specHash(updated) {
+		t.Errorf("new spec not applied: %d changes, status %+v", memory.changes, updated.Status)
+	}
+}
+
+func TestReconcileRenamesRecord(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	r := newTestReconciler(memory, record)
+	_, updated := reconcileRecord(t, r, record)
+	if updated.Status.AppliedRecord == nil || updated.Status.AppliedRecord.Name != "www.example.com" {
+		t.Fatalf("applied record not saved: %+v", updated.Status.AppliedRecord)
+	}
+
+	updated.Spec.Route53Records.Name = "api.example.com"
+	updated.Spec.Route53Records.Type = "A"
+	updated.Spec.Route53Records.ResourceRecords = []string{"10.0.0.1"}
+	if err := r.Update(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	_, updated = reconcileRecord(t, r, record)
+	if _, found := memory.records["www.example.com/CNAME"]; found {
+		t.Error("the old record has not been deleted")
+	}
+	if _, found := memory.records["api.example.com/A"]; !found || updated.Status.AppliedRecord.Name != "api.example.com" {
+		t.Errorf("the new record has not been applied: %v", memory.records)
+	}
+
+	// The finalizer deletes the record last applied
+	updated.Spec.Route53Records.Name = "never-applied.example.com"
+	if err := r.Update(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	memory.err = nil
+	key := types.NamespacedName{Namespace: record.Namespace, Name: record.Name}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if len(memory.records) != 0 {
+		t.Errorf("records left behind: %v", memory.records)
+	}
+}
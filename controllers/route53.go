@@ -2,13 +2,19 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"strings"
 )
@@ -18,110 +24,501 @@ var (
 	ActionDelete = "DELETE"
 )
 
-func (r *DnsRecordReconciler) getAwsCred(ctx context.Context, secretNs, secretName, accessKeyIdKey, accessSecretKeyKey string) (string, string, error) {
-	logger := log.FromContext(ctx)
-	accessId, errSecret := r.GetSecret(ctx, secretNs, secretName, accessKeyIdKey)
-	if errSecret != nil {
-		logger.Error(errSecret, "can't get the aws access key")
-		return "", "", errSecret
+var errSecretKeyNotFound = errors.New("secret key not found")
+
+// ReasonSecretNotAllowed the credentials Secret is in another namespace, and it does not allow the namespace of the resource
+const ReasonSecretNotAllowed = "SecretNotAllowed"
+
+// allowedNamespacesAnnotation lists the namespaces, comma separated, whose resources may use a Secret as credentials.
+// "*" allows every namespace. Resources in the namespace of the Secret can always use it
+const allowedNamespacesAnnotation = "net.beekube.cloud/allowed-namespaces"
+
+// DefaultAwsRegion is used when neither the record nor the provider set a region
+const DefaultAwsRegion = "eu-west-1"
+
+// Route53Provider manages DnsRecords in AWS Route53
+type Route53Provider struct {
+	client.Client
+	// Region default AWS region, used when the record does not set one
+	Region string
+	// Endpoint default Route53 endpoint URL, used when the record does not set one.
+	// Leave it empty to use the AWS public endpoint
+	Endpoint string
+	// OwnerId identifies this operator instance in the owner TXT records,
+	// it must be unique among the clusters sharing a hosted zone
+	OwnerId string
+	// AllowDefaultCredentials lets the resources without a credentials Secret use the default credential chain
+	// of the operator. The ClusterDnsProviders, set by the cluster admins, can always use it
+	AllowDefaultCredentials bool
+	// zones the hosted zones found for the records without a zone id
+	zones *zoneCache
+	// clients the Route53 clients by credential source
+	clients *clientPool
+}
+
+func NewRoute53Provider(c client.Client) *Route53Provider {
+	return &Route53Provider{Client: c, Region: DefaultAwsRegion, OwnerId: DefaultOwnerId, zones: newZoneCache(), clients: newClientPool()}
+}
+
+func (p *Route53Provider) Name() string {
+	return "route53"
+}
+
+func (p *Route53Provider) Handles(record *v1alpha1.DnsRecord) bool {
+	return record.Spec.Route53Records.Name != ""
+}
+
+// Resolve sets the hosted zone of the records without a zone id
+func (p *Route53Provider) Resolve(ctx context.Context, record *v1alpha1.DnsRecord) error {
+	spec := &record.Spec.Route53Records
+	if spec.ZoneId != "" {
+		return nil
+	}
+
+	svc, err := p.route53Client(ctx, record)
+	if err != nil {
+		return err
 	}
-	accessSecret, errSecret := r.GetSecret(ctx, secretNs, secretName, accessSecretKeyKey)
-	if errSecret != nil {
-		logger.Error(errSecret, "can't get the aws secret")
-		return "", "", errSecret
+
+	// Hosted zones belong to the AWS account of the credentials
+	spec.ZoneId, err = findHostedZone(ctx, svc, p.zones, p.recordSource(record).String(), *spec)
+	return err
+}
+
+// ZoneName returns the name of the hosted zone set by the zone id of the record
+func (p *Route53Provider) ZoneName(ctx context.Context, record *v1alpha1.DnsRecord) (string, error) {
+	svc, err := p.route53Client(ctx, record)
+	if err != nil {
+		return "", err
 	}
 
-	return accessId, accessSecret, nil
+	zone, err := p.zones.zoneWithId(ctx, svc, p.recordSource(record).String(), record.Spec.Route53Records.ZoneId)
+	return zone.Name, err
 }
 
-func (r *DnsRecordReconciler) ReconcileRoute53(ctx context.Context, ns string, record v1alpha1.Route53Record) error {
-	secretNs := record.AwsSecrets.SecretNamespace
-	if secretNs == "" {
-		secretNs = ns
+func (p *Route53Provider) Apply(ctx context.Context, record *v1alpha1.DnsRecord) (string, error) {
+	svc, err := p.route53Client(ctx, record)
+	if err != nil {
+		return "", err
+	}
+
+	spec := record.Spec.Route53Records
+	upsert, err := route53Change(ActionUpsert, spec)
+	if err != nil {
+		return "", err
+	}
+	if err := p.checkOwnership(ctx, svc, record); err != nil {
+		return "", err
+	}
+	owner, err := p.ownerChange(ActionUpsert, record, spec)
+	if err != nil {
+		return "", err
+	}
+	changes := []types.Change{upsert, owner}
+
+	if !isRenamed(record) {
+		return changeRoute53(ctx, svc, spec.ZoneId, spec.Comment, changes...)
+	}
+
+	// The record has been renamed: the old record is deleted too
+	previous := appliedRecord(record)
+	deletes, err := p.deleteChanges(ctx, svc, record, previous)
+	if err != nil {
+		return "", err
+	}
+
+	// Same zone: delete and create in the same batch, Route53 applies it atomically
+	if previous.ZoneId == spec.ZoneId {
+		return changeRoute53(ctx, svc, spec.ZoneId, spec.Comment, append(deletes, changes...)...)
 	}
 
-	accessId, accessSecret, err := r.getAwsCred(ctx, secretNs, record.AwsSecrets.SecretName, record.AwsSecrets.AccessKeyIDKey, record.AwsSecrets.SecretAccessKeyKey)
+	// A batch is scoped to a hosted zone: the old record is deleted once the new one has been created
+	changeId, err := changeRoute53(ctx, svc, spec.ZoneId, spec.Comment, changes...)
+	if err != nil || len(deletes) == 0 {
+		return changeId, err
+	}
+	_, err = changeRoute53(ctx, svc, previous.ZoneId, previous.Comment, deletes...)
+	if err != nil && !hasReason(err, ReasonRecordNotFound) && !hasReason(err, ReasonZoneNotFound) {
+		return "", err
+	}
+	return changeId, nil
+}
+
+func (p *Route53Provider) Delete(ctx context.Context, record *v1alpha1.DnsRecord) error {
+	svc, err := p.route53Client(ctx, record)
 	if err != nil {
 		return err
 	}
 
-	return UpsertRoute53(ctx, record, ActionUpsert, accessId, accessSecret)
+	applied := appliedRecord(record)
+	changes, err := p.deleteChanges(ctx, svc, record, applied)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	_, err = changeRoute53(ctx, svc, applied.ZoneId, applied.Comment, changes...)
+	if hasReason(err, ReasonRecordNotFound) || hasReason(err, ReasonZoneNotFound) {
+		log.FromContext(ctx).Info("Record not found, considering the reconciliation completed", "error", err.Error())
+		return nil
+	}
+	return err
 }
 
-func (r *DnsRecordReconciler) FinalizeAwsRoute53(ctx context.Context, ns string, record v1alpha1.Route53Record) error {
-	secretNs := record.AwsSecrets.SecretNamespace
-	if secretNs == "" {
-		secretNs = ns
+func (p *Route53Provider) Current(ctx context.Context, record *v1alpha1.DnsRecord) (*RecordSet, error) {
+	svc, err := p.route53Client(ctx, record)
+	if err != nil {
+		return nil, err
 	}
 
-	accessId, accessSecret, err := r.getAwsCred(ctx, secretNs, record.AwsSecrets.SecretName, record.AwsSecrets.AccessKeyIDKey, record.AwsSecrets.SecretAccessKeyKey)
+	return GetRecordRoute53(ctx, svc, record.Spec.Route53Records)
+}
+
+func (p *Route53Provider) ChangeStatus(ctx context.Context, record *v1alpha1.DnsRecord, changeId string) (bool, error) {
+	svc, err := p.route53Client(ctx, record)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	output, err := GetChangeStatus53(ctx, svc, changeId)
+	if err != nil {
+		return false, err
 	}
 
-	return UpsertRoute53(ctx, record, ActionDelete, accessId, accessSecret)
+	return output.ChangeInfo.Status == types.ChangeStatusInsync, nil
+}
+
+// awsCredentialSource where the credentials, region and endpoint of a Route53 client come from
+type awsCredentialSource struct {
+	// Namespace of the resource using the credentials. It is not part of the source:
+	// the clients are shared by the namespaces allowed to use the Secret
+	Namespace string
+	// Secret static access keys. An empty SecretName means the default credential chain (IRSA, instance role, env)
+	Secret v1alpha1.AwsSecret
+	// RoleArn role assumed with the credentials, if set
+	RoleArn    string
+	ExternalId string
+	Region     string
+	Endpoint   string
+	// ClusterProvider the credentials are set by a ClusterDnsProvider
+	ClusterProvider bool
 }
 
-func GetChangeStatus53(ctx context.Context, changeId, accessId, accessSecret string) (*route53.GetChangeOutput, error) {
-	logger := log.FromContext(ctx)
-	cfg, errConfig := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessId, accessSecret, "")))
-	if errConfig != nil {
-		logger.Error(errConfig, "can't get aws configuration")
-		return nil, errConfig
+// String identifies the source, for example to cache the values that depend on the AWS account
+func (s awsCredentialSource) String() string {
+	return fmt.Sprintf("%s/%s|%s|%s|%s|%s", s.Secret.SecretNamespace, s.Secret.SecretName, s.RoleArn, s.ExternalId, s.Region, s.Endpoint)
+}
+
+// credentialSource returns the source of the credentials set on a resource, with the provider defaults applied.
+// The secret is looked up in namespace if the credentials don't set one
+func (p *Route53Provider) credentialSource(namespace string, secrets v1alpha1.AwsSecret, roleArn, externalId, region, endpoint string) awsCredentialSource {
+	if secrets.SecretNamespace == "" {
+		secrets.SecretNamespace = namespace
+	}
+	if region == "" {
+		region = p.Region
 	}
-	svc := route53.NewFromConfig(cfg)
+	if endpoint == "" {
+		endpoint = p.Endpoint
+	}
+	return awsCredentialSource{Namespace: namespace, Secret: secrets, RoleArn: roleArn, ExternalId: externalId, Region: region, Endpoint: endpoint}
+}
 
-	return svc.GetChange(ctx, &route53.GetChangeInput{Id: aws.String(changeId)})
+// recordSource returns the source of the credentials of the record
+func (p *Route53Provider) recordSource(record *v1alpha1.DnsRecord) awsCredentialSource {
+	spec := record.Spec.Route53Records
+	namespace := record.Namespace
+	// The credentials of a ClusterDnsProvider are set by the cluster admins: they may use a Secret in any namespace
+	clusterProvider := record.Spec.ProviderRef != nil && record.Spec.ProviderRef.Kind == v1alpha1.ClusterDnsProviderKind
+	if clusterProvider {
+		namespace = spec.AwsSecrets.SecretNamespace
+	}
+	source := p.credentialSource(namespace, spec.AwsSecrets, spec.RoleArn, spec.ExternalId, spec.AwsRegion, spec.AwsEndpoint)
+	source.ClusterProvider = clusterProvider
+	return source
+}
+
+// route53Client returns a client for the credentials, region and endpoint of the record
+func (p *Route53Provider) route53Client(ctx context.Context, record *v1alpha1.DnsRecord) (*route53.Client, error) {
+	return p.clientFor(ctx, p.recordSource(record))
 }
 
-func UpsertRoute53(ctx context.Context, record v1alpha1.Route53Record, action, accessId, accessSecret string) error {
-	logger := log.FromContext(ctx)
-	cfg, errConfig := config.LoadDefaultConfig(ctx,
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessId, accessSecret, "")),
-		config.WithRegion("eu-west-1"))
-	if errConfig != nil {
-		logger.Error(errConfig, "can't get aws configuration")
-		return errConfig
+// clientFor returns a client for the given credential source.
+// The clients are reused until the Secret holding the access keys changes
+func (p *Route53Provider) clientFor(ctx context.Context, source awsCredentialSource) (*route53.Client, error) {
+	var secret *v1.Secret
+	secretVersion := ""
+	if source.Secret.SecretName != "" {
+		var err error
+		secret, err = p.credentialSecret(ctx, source.Secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				p.clients.forget(source)
+			}
+			log.FromContext(ctx).Error(err, "can't get the aws credentials secret")
+			return nil, classifySecretError(err)
+		}
+		if err := allowsNamespace(secret, source.Namespace); err != nil {
+			log.FromContext(ctx).Error(err, "can't use the aws credentials secret")
+			return nil, err
+		}
+		secretVersion = secret.ResourceVersion
+	} else if !p.AllowDefaultCredentials && !source.ClusterProvider {
+		// The operator may reach zones the users creating the resources must not
+		return nil, NewTerminalError(ReasonCredentialsError, errors.New("awsSecrets is required: the default credential chain of the operator "+
+			"is only available to the ClusterDnsProviders, unless the operator runs with --allow-default-aws-credentials"))
 	}
 
-	logger.Info(fmt.Sprintf("%s dns record", action))
+	if svc, found := p.clients.get(source, secretVersion); found {
+		return svc, nil
+	}
 
-	var rr []types.ResourceRecord
-	for _, r := range record.ResourceRecords {
-		rr = append(rr, types.ResourceRecord{Value: aws.String(r)})
+	svc, err := p.newClient(ctx, source, secret)
+	if err != nil {
+		return nil, err
 	}
+	p.clients.put(source, secretVersion, svc)
+	return svc, nil
+}
 
-	params := &route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: aws.String(record.ZoneId),
-		ChangeBatch: &types.ChangeBatch{
-			Changes: []types.Change{
-				{
-					Action: types.ChangeAction(action),
-					ResourceRecordSet: &types.ResourceRecordSet{
-						TTL:             aws.Int64(300),
-						Name:            aws.String(record.Name),
-						Type:            types.RRType(record.Type),
-						ResourceRecords: rr,
-					},
-				},
-			},
-			Comment: aws.String(record.Comment),
-		},
+// newClient creates a client for the given credential source. A nil secret means the default credential chain
+func (p *Route53Provider) newClient(ctx context.Context, source awsCredentialSource, secret *v1.Secret) (*route53.Client, error) {
+	options := []func(*config.LoadOptions) error{config.WithRegion(source.Region)}
+	if secret != nil {
+		accessId, accessSecret, err := awsCredentials(secret, source.Secret)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "can't get the aws access keys")
+			return nil, classifySecretError(err)
+		}
+		options = append(options, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessId, accessSecret, "")))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "can't get aws configuration")
+		return nil, NewTerminalError(ReasonCredentialsError, err)
+	}
+	if source.RoleArn != "" {
+		// A custom endpoint, like moto, serves STS too
+		stsClient := sts.NewFromConfig(cfg, func(o *sts.Options) {
+			if source.Endpoint != "" {
+				o.EndpointResolver = sts.EndpointResolverFromURL(source.Endpoint)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsClient, source.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "kube-dns-operator"
+			if source.ExternalId != "" {
+				o.ExternalID = aws.String(source.ExternalId)
+			}
+		}))
 	}
 
-	svc := route53.NewFromConfig(cfg)
-	output, errUpsert := svc.ChangeResourceRecordSets(ctx, params)
-	if errUpsert != nil {
-		if action == "DELETE" && strings.Contains(errUpsert.Error(), "StatusCode: 400") {
-			logger.Error(errUpsert, "Record not found? Considering the reconcilitaion completed")
+	return newRoute53ClientFromConfig(cfg, source.Endpoint), nil
+}
+
+// credentialSecret reads the Secret holding the access keys
+func (p *Route53Provider) credentialSecret(ctx context.Context, secrets v1alpha1.AwsSecret) (*v1.Secret, error) {
+	secret := &v1.Secret{}
+	err := p.Get(ctx, client.ObjectKey{Namespace: secrets.SecretNamespace, Name: secrets.SecretName}, secret)
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// allowsNamespace checks that the resources in namespace may use the Secret as credentials:
+// the operator can read Secrets in any namespace, the users creating DnsRecords might not
+func allowsNamespace(secret *v1.Secret, namespace string) error {
+	if secret.Namespace == namespace {
+		return nil
+	}
+	for _, allowed := range strings.Split(secret.Annotations[allowedNamespacesAnnotation], ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == namespace {
 			return nil
 		}
+	}
+	return NewTerminalError(ReasonSecretNotAllowed, fmt.Errorf("the secret %s/%s does not allow namespace %s in the %s annotation",
+		secret.Namespace, secret.Name, namespace, allowedNamespacesAnnotation))
+}
+
+// awsCredentials returns the access key id and secret stored in secret
+func awsCredentials(secret *v1.Secret, secrets v1alpha1.AwsSecret) (string, string, error) {
+	accessId, hasId := secret.Data[secrets.AccessKeyIDKey]
+	if !hasId {
+		return "", "", fmt.Errorf("%w: %s", errSecretKeyNotFound, secrets.AccessKeyIDKey)
+	}
+	accessSecret, hasSecret := secret.Data[secrets.SecretAccessKeyKey]
+	if !hasSecret {
+		return "", "", fmt.Errorf("%w: %s", errSecretKeyNotFound, secrets.SecretAccessKeyKey)
+	}
+	return string(accessId), string(accessSecret), nil
+}
+
+// classifySecretError a missing secret or key won't appear by retrying, while any other error of the k8s api might
+func classifySecretError(err error) error {
+	if apierrors.IsNotFound(err) || errors.Is(err, errSecretKeyNotFound) {
+		return NewTerminalError(ReasonCredentialsError, err)
+	}
+	return NewRetryableError(ReasonCredentialsError, err)
+}
+
+// newRoute53ClientFromConfig creates a Route53 client. An empty endpoint means the AWS public endpoint
+func newRoute53ClientFromConfig(cfg aws.Config, endpoint string) *route53.Client {
+	return route53.NewFromConfig(cfg, func(o *route53.Options) {
+		if endpoint != "" {
+			o.EndpointResolver = route53.EndpointResolverFromURL(endpoint)
+		}
+	})
+}
+
+func GetChangeStatus53(ctx context.Context, svc *route53.Client, changeId string) (*route53.GetChangeOutput, error) {
+	output, err := svc.GetChange(ctx, &route53.GetChangeInput{Id: aws.String(changeId)})
+	return output, classifyRoute53Error(err)
+}
+
+// GetRecordRoute53 returns the record set matching the record name and type, or nil if it does not exist
+func GetRecordRoute53(ctx context.Context, svc *route53.Client, record v1alpha1.Route53Record) (*RecordSet, error) {
+	input := &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(record.ZoneId),
+		StartRecordName: aws.String(record.Name),
+		StartRecordType: types.RRType(record.Type),
+		MaxItems:        aws.Int32(1),
+	}
+	if record.SetIdentifier != "" {
+		input.StartRecordIdentifier = aws.String(record.SetIdentifier)
+	}
+	output, err := svc.ListResourceRecordSets(ctx, input)
+	if err != nil {
+		return nil, classifyRoute53Error(err)
+	}
+
+	for _, set := range output.ResourceRecordSets {
+		if normalizeDnsName(unescapeRoute53Name(aws.ToString(set.Name))) != normalizeDnsName(record.Name) ||
+			string(set.Type) != record.Type ||
+			aws.ToString(set.SetIdentifier) != record.SetIdentifier {
+			continue
+		}
+
+		current := &RecordSet{
+			Name: aws.ToString(set.Name),
+			Type: string(set.Type),
+			Ttl:  aws.ToInt64(set.TTL),
+		}
+		if set.AliasTarget != nil {
+			current.Alias = &v1alpha1.Route53Alias{
+				DNSName:              aws.ToString(set.AliasTarget.DNSName),
+				HostedZoneId:         aws.ToString(set.AliasTarget.HostedZoneId),
+				EvaluateTargetHealth: set.AliasTarget.EvaluateTargetHealth,
+			}
+		}
+		current.Routing = route53Routing(set)
+		for _, rr := range set.ResourceRecords {
+			current.Values = append(current.Values, aws.ToString(rr.Value))
+		}
+		return current, nil
+	}
+
+	return nil, nil
+}
+
+// route53Change returns the change of the record described by the spec
+func route53Change(action string, record v1alpha1.Route53Record) (types.Change, error) {
+	if err := record.Validate(); err != nil {
+		return types.Change{}, NewTerminalError(ReasonInvalidRecord, err)
+	}
+
+	return recordSetChange(action, recordSetOf(record)), nil
+}
+
+// recordSetChange returns the change of a record set
+func recordSetChange(action string, set RecordSet) types.Change {
+	recordSet := &types.ResourceRecordSet{
+		Name: aws.String(set.Name),
+		Type: types.RRType(set.Type),
+	}
+
+	// Alias records have neither a ttl nor values
+	if set.Alias != nil {
+		recordSet.AliasTarget = &types.AliasTarget{
+			DNSName:              aws.String(set.Alias.DNSName),
+			HostedZoneId:         aws.String(set.Alias.HostedZoneId),
+			EvaluateTargetHealth: set.Alias.EvaluateTargetHealth,
+		}
+	} else {
+		recordSet.TTL = aws.Int64(set.Ttl)
+		for _, r := range set.Values {
+			recordSet.ResourceRecords = append(recordSet.ResourceRecords, types.ResourceRecord{Value: aws.String(r)})
+		}
+	}
+
+	routing := set.Routing
+	if routing.SetIdentifier != "" {
+		recordSet.SetIdentifier = aws.String(routing.SetIdentifier)
+	}
+	recordSet.Weight = routing.Weight
+	recordSet.Region = types.ResourceRecordSetRegion(routing.Region)
+	recordSet.Failover = types.ResourceRecordSetFailover(routing.Failover)
+	if routing.HealthCheckId != "" {
+		recordSet.HealthCheckId = aws.String(routing.HealthCheckId)
+	}
+	if location := routing.GeoLocation; location != nil {
+		recordSet.GeoLocation = &types.GeoLocation{}
+		if location.ContinentCode != "" {
+			recordSet.GeoLocation.ContinentCode = aws.String(location.ContinentCode)
+		}
+		if location.CountryCode != "" {
+			recordSet.GeoLocation.CountryCode = aws.String(location.CountryCode)
+		}
+		if location.SubdivisionCode != "" {
+			recordSet.GeoLocation.SubdivisionCode = aws.String(location.SubdivisionCode)
+		}
+	}
 
-		logger.Error(errUpsert, "failed aws api call :(")
-		return errUpsert
+	return types.Change{
+		Action:            types.ChangeAction(action),
+		ResourceRecordSet: recordSet,
 	}
+}
 
-	logger.Info("change committed", "changeId", output.ChangeInfo.Id)
+// route53Routing returns the routing policy of a Route53 record set
+func route53Routing(set types.ResourceRecordSet) v1alpha1.Route53Routing {
+	routing := v1alpha1.Route53Routing{
+		SetIdentifier: aws.ToString(set.SetIdentifier),
+		Weight:        set.Weight,
+		Region:        string(set.Region),
+		Failover:      string(set.Failover),
+		HealthCheckId: aws.ToString(set.HealthCheckId),
+	}
+	if set.GeoLocation != nil {
+		routing.GeoLocation = &v1alpha1.Route53GeoLocation{
+			ContinentCode:   aws.ToString(set.GeoLocation.ContinentCode),
+			CountryCode:     aws.ToString(set.GeoLocation.CountryCode),
+			SubdivisionCode: aws.ToString(set.GeoLocation.SubdivisionCode),
+		}
+	}
+	return routing
+}
+
+// changeRoute53 submits the changes to the hosted zone in a single batch, that Route53 applies atomically.
+// It returns the id of the Route53 change
+func changeRoute53(ctx context.Context, svc *route53.Client, zoneId, comment string, changes ...types.Change) (string, error) {
+	output, err := svc.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneId),
+		ChangeBatch: &types.ChangeBatch{
+			Changes: changes,
+			Comment: aws.String(comment),
+		},
+	})
+	if err != nil {
+		return "", classifyRoute53Error(err)
+	}
+
+	log.FromContext(ctx).Info("change committed", "changeId", output.ChangeInfo.Id)
+
+	return aws.ToString(output.ChangeInfo.Id), nil
+}
 
-	return nil
+// unescapeRoute53Name Route53 returns the wildcard '*' escaped as octal
+func unescapeRoute53Name(name string) string {
+	return strings.ReplaceAll(name, "\\052", "*")
 }
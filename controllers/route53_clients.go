@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"sync"
+)
+
+// clientPool reuses the Route53 clients, and the credentials they cache, across reconciles.
+// A client built from a Secret is valid only for the resourceVersion it was built from
+type clientPool struct {
+	sync.Mutex
+	entries map[string]clientPoolEntry
+}
+
+type clientPoolEntry struct {
+	client *route53.Client
+	// secretVersion resourceVersion of the Secret the credentials were read from, empty for the default chain
+	secretVersion string
+}
+
+func newClientPool() *clientPool {
+	return &clientPool{entries: map[string]clientPoolEntry{}}
+}
+
+// get returns the client of the source, if it was built from the given version of its Secret
+func (c *clientPool) get(source awsCredentialSource, secretVersion string) (*route53.Client, bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, found := c.entries[source.String()]
+	if !found || entry.secretVersion != secretVersion {
+		return nil, false
+	}
+	return entry.client, true
+}
+
+// put stores the client of the source, replacing the one built from a previous version of the Secret
+func (c *clientPool) put(source awsCredentialSource, secretVersion string, svc *route53.Client) {
+	c.Lock()
+	defer c.Unlock()
+	c.entries[source.String()] = clientPoolEntry{client: svc, secretVersion: secretVersion}
+}
+
+// forget drops the client of the source, for example when its Secret is gone
+func (c *clientPool) forget(source awsCredentialSource) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.entries, source.String())
+}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"testing"
+)
+
+func TestRoute53UsesDefaultCredentialChain(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "ENVSECRET")
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+
+	provider := newTestRoute53Provider(fake)
+
+	// The users creating DnsRecords must not get the credentials of the operator
+	record := newTestRecord()
+	_, err := provider.Apply(context.Background(), record)
+	if providerError := ClassifyError(err); providerError == nil || providerError.Reason != ReasonCredentialsError || providerError.Retryable {
+		t.Fatalf("expected a terminal CredentialsError, got %v", err)
+	}
+	if len(fake.authorizations) != 0 {
+		t.Fatal("route53 must not be called with the credentials of the operator")
+	}
+
+	// A ClusterDnsProvider is set by the cluster admins
+	record.Spec.ProviderRef = &netv1alpha1.DnsProviderRef{Kind: netv1alpha1.ClusterDnsProviderKind, Name: "route53"}
+	if _, err := provider.Apply(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	record.Spec.ProviderRef = nil
+	provider.AllowDefaultCredentials = true
+	if _, err := provider.Apply(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	for _, authorization := range fake.authorizations {
+		if !strings.Contains(authorization, "Credential=ENVKEY/") {
+			t.Errorf("expected the credentials of the environment, got %s", authorization)
+		}
+	}
+}
+
+const assumeRoleResponse = `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult><Credentials><AccessKeyId>ASSUMEDKEY</AccessKeyId><SecretAccessKey>ASSUMEDSECRET</SecretAccessKey><SessionToken>TOKEN</SessionToken><Expiration>2100-01-01T00:00:00Z</Expiration></Credentials><AssumedRoleUser><Arn>arn:aws:sts::123456789012:assumed-role/dns/kube-dns-operator</Arn><AssumedRoleId>ROLEID:kube-dns-operator</AssumedRoleId></AssumedRoleUser></AssumeRoleResult></AssumeRoleResponse>`
+
+func TestRoute53AssumesRole(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	fake.responses["POST /"] = assumeRoleResponse
+
+	record := newTestRoute53Record()
+	record.Spec.Route53Records.RoleArn = "arn:aws:iam::123456789012:role/dns"
+	record.Spec.Route53Records.ExternalId = "ext-1"
+	if _, err := newTestRoute53Provider(fake).Apply(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	assumeRole := fake.bodies("POST /")
+	if len(assumeRole) != 1 || !strings.Contains(assumeRole[0], "ExternalId=ext-1") || !strings.Contains(assumeRole[0], "RoleArn=arn%3Aaws%3Aiam%3A%3A123456789012%3Arole%2Fdns") {
+		t.Fatalf("unexpected AssumeRole requests: %v", assumeRole)
+	}
+	if !strings.Contains(fake.authorizations[0], "Credential=AKID/") {
+		t.Errorf("the role must be assumed with the credentials of the secret: %s", fake.authorizations[0])
+	}
+	last := fake.authorizations[len(fake.authorizations)-1]
+	if !strings.Contains(last, "Credential=ASSUMEDKEY/") {
+		t.Errorf("expected the credentials of the assumed role, got %s", last)
+	}
+}
+
+func TestRoute53ReusesClientUntilSecretChanges(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	fake.responses["POST /"] = assumeRoleResponse
+	provider := newTestRoute53Provider(fake)
+
+	record := newTestRoute53Record()
+	record.Spec.Route53Records.RoleArn = "arn:aws:iam::123456789012:role/dns"
+	first, err := provider.route53Client(ctx, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := provider.Apply(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if second, _ := provider.route53Client(ctx, record); second != first {
+		t.Errorf("expected the client to be reused")
+	}
+	if assumeRole := fake.bodies("POST /"); len(assumeRole) != 1 {
+		t.Errorf("expected the assumed role credentials to be cached, got %d AssumeRole requests", len(assumeRole))
+	}
+
+	secret := &v1.Secret{}
+	if err := provider.Get(ctx, client.ObjectKey{Namespace: "default", Name: "aws"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	secret.Data["id"] = []byte("ROTATED")
+	if err := provider.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Apply(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	assumeRole := fake.bodies("POST /")
+	if len(assumeRole) != 2 {
+		t.Fatalf("expected a new client after the secret changed, got %d AssumeRole requests", len(assumeRole))
+	}
+	rotated := false
+	for _, authorization := range fake.authorizations {
+		rotated = rotated || strings.Contains(authorization, "Credential=ROTATED/")
+	}
+	if !rotated {
+		t.Errorf("the role must be assumed with the rotated credentials: %v", fake.authorizations)
+	}
+}
+
+func TestReconcileRetriesWhenSecretIsCreated(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	record := newTestRoute53Record()
+	r := newTestRoute53Reconciler(fake, record)
+
+	_, updated := reconcileRecord(t, r, record)
+	if updated.Status.Status != StatusError {
+		t.Fatalf("expected the record to fail without its secret, got %s", updated.Status.Status)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default"},
+		Data:       map[string][]byte{"id": []byte("AKID"), "secret": []byte("SECRET")},
+	}
+	if err := r.Create(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if requests := r.dnsRecordsForSecret(secret); len(requests) != 1 || requests[0].Name != record.Name {
+		t.Fatalf("the DnsRecord using the secret must be reconciled: %v", requests)
+	}
+	if _, updated = reconcileRecord(t, r, record); updated.Status.Status != StatusSynced {
+		t.Errorf("expected the record to be synced once the secret exists, got %s", updated.Status.Status)
+	}
+}
+
+func TestIndexSecret(t *testing.T) {
+	record := newTestRoute53Record()
+	if keys := indexSecret(record); len(keys) != 1 || keys[0] != "default/aws" {
+		t.Errorf("expected the secret in the namespace of the record, got %v", keys)
+	}
+
+	record.Spec.Route53Records.AwsSecrets.SecretNamespace = "credentials"
+	if keys := indexSecret(record); len(keys) != 1 || keys[0] != "credentials/aws" {
+		t.Errorf("expected the secret namespace of the credentials, got %v", keys)
+	}
+
+	record.Spec.Route53Records.AwsSecrets = netv1alpha1.AwsSecret{}
+	if keys := indexSecret(record); len(keys) != 0 {
+		t.Errorf("the default credential chain must not be indexed, got %v", keys)
+	}
+}
+
+func TestRoute53RequiresGrantForCrossNamespaceSecret(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	provider := newTestRoute53Provider(fake)
+
+	record := newTestRoute53Record()
+	record.Namespace = "team-a"
+	record.Spec.Route53Records.AwsSecrets.SecretNamespace = "default"
+	_, err := provider.Apply(ctx, record)
+	if providerError := ClassifyError(err); providerError == nil || providerError.Reason != ReasonSecretNotAllowed || providerError.Retryable {
+		t.Fatalf("expected a terminal SecretNotAllowed error, got %v", err)
+	}
+	if len(fake.authorizations) != 0 {
+		t.Fatal("route53 must not be called with the credentials of another namespace")
+	}
+
+	secret := &v1.Secret{}
+	if err := provider.Get(ctx, client.ObjectKey{Namespace: "default", Name: "aws"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	secret.Annotations = map[string]string{allowedNamespacesAnnotation: "team-b, team-a"}
+	if err := provider.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Apply(ctx, record); err != nil {
+		t.Errorf("the namespaces listed in the annotation must be allowed: %v", err)
+	}
+}
+
+// newTestRoute53Reconciler returns a reconciler applying the records to the fake
+func newTestRoute53Reconciler(fake *fakeRoute53, objs ...client.Object) *DnsRecordReconciler {
+	r := newTestReconciler(newMemoryProvider(), objs...)
+	provider := NewRoute53Provider(r.Client)
+	provider.Endpoint = fake.URL
+	r.Providers = NewProviderRegistry(provider)
+	return r
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"strings"
+)
+
+// Reasons specific to the Route53 errors
+const (
+	ReasonRecordNotFound          = "RecordNotFound"
+	ReasonZoneNotFound            = "ZoneNotFound"
+	ReasonChangeNotFound          = "ChangeNotFound"
+	ReasonPriorRequestNotComplete = "PriorRequestNotComplete"
+	ReasonConcurrentModification  = "ConcurrentModification"
+	ReasonLimitExceeded           = "LimitExceeded"
+	ReasonHealthCheckNotFound     = "HealthCheckNotFound"
+	ReasonHealthCheckInUse        = "HealthCheckInUse"
+	ReasonHealthCheckExists       = "HealthCheckAlreadyExists"
+)
+
+// classifyRoute53Error maps the typed Route53 errors to a ProviderError.
+// Errors that are not known Route53 errors are classified by ClassifyError
+func classifyRoute53Error(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var providerError *ProviderError
+	if errors.As(err, &providerError) {
+		return err
+	}
+
+	var invalidChangeBatch *types.InvalidChangeBatch
+	if errors.As(err, &invalidChangeBatch) {
+		// Route53 rejects the DELETE of a missing record with:
+		// "Tried to delete resource record set [...] but it was not found"
+		for _, message := range append(invalidChangeBatch.Messages, invalidChangeBatch.ErrorMessage()) {
+			if strings.Contains(message, "but it was not found") {
+				return NewTerminalError(ReasonRecordNotFound, err)
+			}
+		}
+		return NewTerminalError(ReasonInvalidRecord, err)
+	}
+
+	var (
+		invalidInput            *types.InvalidInput
+		invalidDomainName       *types.InvalidDomainName
+		noSuchHostedZone        *types.NoSuchHostedZone
+		hostedZoneNotFound      *types.HostedZoneNotFound
+		noSuchChange            *types.NoSuchChange
+		priorRequestNotComplete *types.PriorRequestNotComplete
+		concurrentModification  *types.ConcurrentModification
+		throttling              *types.ThrottlingException
+		notAuthorized           *types.NotAuthorizedException
+		limitsExceeded          *types.LimitsExceeded
+		tooManyHealthChecks     *types.TooManyHealthChecks
+		noSuchHealthCheck       *types.NoSuchHealthCheck
+		healthCheckInUse        *types.HealthCheckInUse
+		healthCheckExists       *types.HealthCheckAlreadyExists
+		healthCheckVersion      *types.HealthCheckVersionMismatch
+	)
+	switch {
+	case errors.As(err, &invalidInput), errors.As(err, &invalidDomainName):
+		return NewTerminalError(ReasonInvalidRecord, err)
+	case errors.As(err, &noSuchHostedZone), errors.As(err, &hostedZoneNotFound):
+		return NewTerminalError(ReasonZoneNotFound, err)
+	case errors.As(err, &noSuchChange):
+		return NewTerminalError(ReasonChangeNotFound, err)
+	case errors.As(err, &priorRequestNotComplete):
+		return NewRetryableError(ReasonPriorRequestNotComplete, err)
+	case errors.As(err, &concurrentModification):
+		return NewRetryableError(ReasonConcurrentModification, err)
+	case errors.As(err, &throttling):
+		return NewRetryableError(ReasonThrottled, err)
+	case errors.As(err, &notAuthorized):
+		return NewTerminalError(ReasonCredentialsError, err)
+	case errors.As(err, &limitsExceeded), errors.As(err, &tooManyHealthChecks):
+		return NewTerminalError(ReasonLimitExceeded, err)
+	case errors.As(err, &noSuchHealthCheck):
+		return NewTerminalError(ReasonHealthCheckNotFound, err)
+	case errors.As(err, &healthCheckInUse):
+		// The records using the health check are being updated or deleted
+		return NewRetryableError(ReasonHealthCheckInUse, err)
+	case errors.As(err, &healthCheckExists):
+		return NewTerminalError(ReasonHealthCheckExists, err)
+	case errors.As(err, &healthCheckVersion):
+		return NewRetryableError(ReasonConcurrentModification, err)
+	}
+
+	// Errors without a dedicated type in the Route53 model
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) {
+		switch apiError.ErrorCode() {
+		case "Throttling", "ThrottlingException", "RequestThrottled", "TooManyRequestsException":
+			return NewRetryableError(ReasonThrottled, err)
+		case "AccessDenied", "AccessDeniedException", "InvalidClientTokenId", "SignatureDoesNotMatch",
+			"UnrecognizedClientException", "ExpiredToken", "ExpiredTokenException":
+			return NewTerminalError(ReasonCredentialsError, err)
+		}
+	}
+
+	return ClassifyError(err)
+}
+
+// hasReason returns true if err is a ProviderError with the given reason
+func hasReason(err error, reason string) bool {
+	var providerError *ProviderError
+	return errors.As(err, &providerError) && providerError.Reason == reason
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"testing"
+)
+
+func TestClassifyRoute53Error(t *testing.T) {
+	cases := []struct {
+		err       error
+		reason    string
+		retryable bool
+	}{
+		{&types.InvalidChangeBatch{Messages: []string{"Tried to delete resource record set [name='www.example.com.', type='A'] but it was not found"}}, ReasonRecordNotFound, false},
+		{&types.InvalidChangeBatch{Message: aws.String("RRSet of type CNAME with DNS name www.example.com. is not permitted")}, ReasonInvalidRecord, false},
+		{&types.NoSuchHostedZone{}, ReasonZoneNotFound, false},
+		{&types.PriorRequestNotComplete{}, ReasonPriorRequestNotComplete, true},
+		{&types.ThrottlingException{}, ReasonThrottled, true},
+		{&smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}, ReasonThrottled, true},
+		{&smithy.GenericAPIError{Code: "AccessDenied"}, ReasonCredentialsError, false},
+		{fmt.Errorf("operation error: %w", &types.InvalidInput{}), ReasonInvalidRecord, false},
+		{fmt.Errorf("something else"), ReasonProviderError, true},
+	}
+
+	for _, c := range cases {
+		classified := ClassifyError(classifyRoute53Error(c.err))
+		if classified.Reason != c.reason || classified.Retryable != c.retryable {
+			t.Errorf("%v: expected %s/%v, got %s/%v", c.err, c.reason, c.retryable, classified.Reason, classified.Retryable)
+		}
+	}
+}
+
+func TestDeleteMissingRoute53Record(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse(
+		recordSetXml("www.example.com.", "CNAME", "lb.example.com"),
+		recordSetXml("_kdo-cname.www.example.com.", "TXT", ownedByWww))
+	fake.statuses["POST /2013-04-01/hostedzone/"] = 400
+	fake.responses["POST /2013-04-01/hostedzone/"] = `<InvalidChangeBatch xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><Messages><Message>Tried to delete resource record set [name='www.example.com.', type='CNAME'] but it was not found</Message></Messages><RequestId>r1</RequestId></InvalidChangeBatch>`
+	provider := newTestRoute53Provider(fake)
+
+	if err := provider.Delete(ctx, newTestRoute53Record()); err != nil {
+		t.Errorf("deleting a missing record is not an error: %v", err)
+	}
+
+	_, err := provider.Apply(ctx, newTestRoute53Record())
+	if !hasReason(err, ReasonRecordNotFound) {
+		t.Errorf("expected a classified error, got %v", err)
+	}
+}
+
+func TestDeleteRoute53ReportsInvalidBatch(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse(
+		recordSetXml("www.example.com.", "CNAME", "lb.example.com"),
+		recordSetXml("_kdo-cname.www.example.com.", "TXT", ownedByWww))
+	fake.statuses["POST /2013-04-01/hostedzone/"] = 400
+	fake.responses["POST /2013-04-01/hostedzone/"] = `<InvalidChangeBatch xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><Messages><Message>Tried to delete resource record set [name='www.example.com.', type='CNAME'] but the values provided do not match the current values</Message></Messages><RequestId>r1</RequestId></InvalidChangeBatch>`
+
+	err := newTestRoute53Provider(fake).Delete(context.Background(), newTestRoute53Record())
+	if !hasReason(err, ReasonInvalidRecord) {
+		t.Errorf("an invalid batch must not be swallowed, got %v", err)
+	}
+}
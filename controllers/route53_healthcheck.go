@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// healthCheckConfig returns the Route53 configuration of the health check.
+// children are the Route53 ids of the child health checks
+func healthCheckConfig(spec v1alpha1.Route53HealthCheckSpec, children []string) *types.HealthCheckConfig {
+	config := &types.HealthCheckConfig{
+		Type:              types.HealthCheckType(spec.Type),
+		ChildHealthChecks: children,
+	}
+	if spec.Type == v1alpha1.HealthCheckCalculated {
+		config.HealthThreshold = aws.Int32(spec.HealthThreshold)
+		return config
+	}
+
+	if spec.Fqdn != "" {
+		config.FullyQualifiedDomainName = aws.String(spec.Fqdn)
+	}
+	if spec.IPAddress != "" {
+		config.IPAddress = aws.String(spec.IPAddress)
+	}
+	if spec.Port != 0 {
+		config.Port = aws.Int32(spec.Port)
+	}
+	if spec.Path != "" {
+		config.ResourcePath = aws.String(spec.Path)
+	}
+	if spec.RequestInterval != 0 {
+		config.RequestInterval = aws.Int32(spec.RequestInterval)
+	}
+	if spec.FailureThreshold != 0 {
+		config.FailureThreshold = aws.Int32(spec.FailureThreshold)
+	}
+	for _, region := range spec.Regions {
+		config.Regions = append(config.Regions, types.HealthCheckRegion(region))
+	}
+	if spec.Type == v1alpha1.HealthCheckHttps {
+		config.EnableSNI = aws.Bool(spec.Fqdn != "")
+	}
+	return config
+}
+
+// CreateHealthCheckRoute53 creates the health check and returns its id.
+// Route53 returns the existing health check when called again with the same callerReference and config
+func CreateHealthCheckRoute53(ctx context.Context, svc *route53.Client, callerReference string, config *types.HealthCheckConfig) (string, error) {
+	output, err := svc.CreateHealthCheck(ctx, &route53.CreateHealthCheckInput{
+		CallerReference:   aws.String(callerReference),
+		HealthCheckConfig: config,
+	})
+	if err != nil {
+		return "", classifyRoute53Error(err)
+	}
+
+	id := aws.ToString(output.HealthCheck.Id)
+	log.FromContext(ctx).Info("health check created", "healthCheckId", id)
+	return id, nil
+}
+
+// UpdateHealthCheckRoute53 updates the health check. Type and request interval can't be changed:
+// updating them returns a terminal error
+func UpdateHealthCheckRoute53(ctx context.Context, svc *route53.Client, id string, config *types.HealthCheckConfig) error {
+	current, err := svc.GetHealthCheck(ctx, &route53.GetHealthCheckInput{HealthCheckId: aws.String(id)})
+	if err != nil {
+		return classifyRoute53Error(err)
+	}
+	live := current.HealthCheck.HealthCheckConfig
+	if live.Type != config.Type || (config.RequestInterval != nil && aws.ToInt32(live.RequestInterval) != aws.ToInt32(config.RequestInterval)) {
+		return NewTerminalError(ReasonInvalidRecord, fmt.Errorf("type and requestInterval of health check %s can't be changed, recreate the Route53HealthCheck", id))
+	}
+
+	input := &route53.UpdateHealthCheckInput{
+		HealthCheckId:            aws.String(id),
+		FullyQualifiedDomainName: config.FullyQualifiedDomainName,
+		IPAddress:                config.IPAddress,
+		Port:                     config.Port,
+		ResourcePath:             config.ResourcePath,
+		FailureThreshold:         config.FailureThreshold,
+		Regions:                  config.Regions,
+		ChildHealthChecks:        config.ChildHealthChecks,
+		HealthThreshold:          config.HealthThreshold,
+		EnableSNI:                config.EnableSNI,
+	}
+	// Fields removed from the spec must be reset explicitly
+	if config.FullyQualifiedDomainName == nil && live.FullyQualifiedDomainName != nil {
+		input.ResetElements = append(input.ResetElements, types.ResettableElementNameFullyQualifiedDomainName)
+	}
+	if config.ResourcePath == nil && live.ResourcePath != nil {
+		input.ResetElements = append(input.ResetElements, types.ResettableElementNameResourcePath)
+	}
+	if len(config.Regions) == 0 && len(live.Regions) > 0 {
+		input.ResetElements = append(input.ResetElements, types.ResettableElementNameRegions)
+	}
+	if len(config.ChildHealthChecks) == 0 && len(live.ChildHealthChecks) > 0 {
+		input.ResetElements = append(input.ResetElements, types.ResettableElementNameChildHealthChecks)
+	}
+
+	if _, err := svc.UpdateHealthCheck(ctx, input); err != nil {
+		return classifyRoute53Error(err)
+	}
+	log.FromContext(ctx).Info("health check updated", "healthCheckId", id)
+	return nil
+}
+
+// DeleteHealthCheckRoute53 deletes the health check. Deleting a health check that does not exist is not an error
+func DeleteHealthCheckRoute53(ctx context.Context, svc *route53.Client, id string) error {
+	_, err := svc.DeleteHealthCheck(ctx, &route53.DeleteHealthCheckInput{HealthCheckId: aws.String(id)})
+	err = classifyRoute53Error(err)
+	if hasReason(err, ReasonHealthCheckNotFound) {
+		log.FromContext(ctx).Info("Health check not found, considering the deletion completed", "healthCheckId", id)
+		return nil
+	}
+	return err
+}
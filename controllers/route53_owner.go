@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"strings"
+)
+
+// ReasonNotOwner the record exists in the dns and is not owned by the DnsRecord
+const ReasonNotOwner = "NotOwner"
+
+const (
+	// DefaultOwnerId is used when the operator is started without --owner-id
+	DefaultOwnerId = "default"
+	// ownerRecordPrefix the owner of a record is saved in a TXT record named _kdo-<type>.<record name>
+	ownerRecordPrefix = "_kdo-"
+	// ownerWildcardPrefix the owner of a wildcard record *.<zone> is saved in a TXT record named
+	// _kdo-wildcard-<type>.<zone>: the wildcard label is valid only as the first label of a name
+	ownerWildcardPrefix = "_kdo-wildcard-"
+	// ownerHeritage marks the TXT records written by the operator
+	ownerHeritage = "kube-dns-operator"
+)
+
+// ownerRecord returns the TXT record holding the owner of the record.
+// Each member of a routing set has its own owner record, a weighted TXT record with the same set identifier
+func ownerRecord(record v1alpha1.Route53Record) v1alpha1.Route53Record {
+	name := ownerRecordPrefix + strings.ToLower(record.Type) + "." + record.Name
+	if strings.HasPrefix(record.Name, "*.") {
+		name = ownerWildcardPrefix + strings.ToLower(record.Type) + "." + strings.TrimPrefix(record.Name, "*.")
+	}
+	owner := v1alpha1.Route53Record{
+		ZoneId:  record.ZoneId,
+		Name:    name,
+		Type:    "TXT",
+		Ttl:     record.Ttl,
+		Comment: record.Comment,
+	}
+	if record.SetIdentifier != "" {
+		owner.SetIdentifier = record.SetIdentifier
+		owner.Weight = aws.Int64(0)
+	}
+	return owner
+}
+
+// ownerValue identifies the operator instance and the DnsRecord that own a record
+func (p *Route53Provider) ownerValue(record *v1alpha1.DnsRecord) string {
+	return fmt.Sprintf("heritage=%s,owner=%s,resource=dnsrecord/%s/%s", ownerHeritage, p.OwnerId, record.Namespace, record.Name)
+}
+
+// owns returns true if the owner TXT record is the one written for the DnsRecord
+func (p *Route53Provider) owns(record *v1alpha1.DnsRecord, owner *RecordSet) bool {
+	for _, value := range owner.Values {
+		if strings.Trim(value, `"`) == p.ownerValue(record) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerChange returns the change of the owner TXT record of target
+func (p *Route53Provider) ownerChange(action string, record *v1alpha1.DnsRecord, target v1alpha1.Route53Record) (types.Change, error) {
+	owner := ownerRecord(target)
+	owner.ResourceRecords = []string{`"` + p.ownerValue(record) + `"`}
+	return route53Change(action, owner)
+}
+
+// describeRecord returns the type, name and set identifier of the record
+func describeRecord(record v1alpha1.Route53Record) string {
+	if record.SetIdentifier != "" {
+		return fmt.Sprintf("%s %s (%s)", record.Type, record.Name, record.SetIdentifier)
+	}
+	return fmt.Sprintf("%s %s", record.Type, record.Name)
+}
+
+// checkOwnership returns a terminal error if the record exists in Route53 and the adopt policy
+// of the DnsRecord does not allow to take it over
+func (p *Route53Provider) checkOwnership(ctx context.Context, svc *route53.Client, record *v1alpha1.DnsRecord) error {
+	spec := record.Spec.Route53Records
+	policy := record.Spec.AdoptPolicy
+	if policy == "" {
+		policy = v1alpha1.AdoptPolicyNever
+	}
+
+	owner, err := GetRecordRoute53(ctx, svc, ownerRecord(spec))
+	if err != nil {
+		return err
+	}
+	if owner != nil {
+		if p.owns(record, owner) {
+			return nil
+		}
+		if policy == v1alpha1.AdoptPolicyAlways {
+			log.FromContext(ctx).Info("Taking over a record owned by somebody else", "owner", owner.Values)
+			return nil
+		}
+		return NewTerminalError(ReasonNotOwner, fmt.Errorf("%s is owned by %s", describeRecord(spec), strings.Join(owner.Values, ",")))
+	}
+
+	// Records applied before the ownership registry existed have no owner TXT record:
+	// the finalizer tells that the operator created them
+	if !isRenamed(record) && controllerutil.ContainsFinalizer(record, dnsRecordFinalizer) {
+		return nil
+	}
+	if policy != v1alpha1.AdoptPolicyNever {
+		return nil
+	}
+
+	current, err := GetRecordRoute53(ctx, svc, spec)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		return NewTerminalError(ReasonNotOwner, fmt.Errorf("%s already exists and is not managed by the operator, set adoptPolicy to take it over", describeRecord(spec)))
+	}
+	return nil
+}
+
+// deleteChanges returns the changes removing target and its owner TXT record, as they are now in Route53.
+// Records owned by somebody else are left alone
+func (p *Route53Provider) deleteChanges(ctx context.Context, svc *route53.Client, record *v1alpha1.DnsRecord, target v1alpha1.Route53Record) ([]types.Change, error) {
+	owner, err := GetRecordRoute53(ctx, svc, ownerRecord(target))
+	if hasReason(err, ReasonZoneNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil && !p.owns(record, owner) {
+		log.FromContext(ctx).Info("The record is owned by somebody else, it won't be deleted", "name", target.Name, "owner", owner.Values)
+		return nil, nil
+	}
+
+	// A DELETE must match the live values exactly
+	current, err := GetRecordRoute53(ctx, svc, target)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []types.Change
+	if current != nil {
+		changes = append(changes, recordSetChange(ActionDelete, *current))
+	}
+	if owner != nil {
+		changes = append(changes, recordSetChange(ActionDelete, *owner))
+	}
+	return changes, nil
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"strings"
+	"testing"
+)
+
+const ownedByWww = `"heritage=kube-dns-operator,owner=default,resource=dnsrecord/default/www"`
+
+func TestRoute53ApplyWritesOwner(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	provider := newTestRoute53Provider(fake)
+
+	if _, err := provider.Apply(context.Background(), newTestRoute53Record()); err != nil {
+		t.Fatal(err)
+	}
+	bodies := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")
+	if len(bodies) != 1 || !strings.Contains(bodies[0], "<Name>_kdo-cname.www.example.com</Name>") || !strings.Contains(bodies[0], strings.Trim(ownedByWww, `"`)) {
+		t.Errorf("the owner record must be written with the record: %v", bodies)
+	}
+}
+
+func TestRoute53ApplyWildcardRecord(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	record := newTestRoute53Record()
+	record.Spec.Route53Records.Name = "*.example.com."
+
+	if _, err := newTestRoute53Provider(fake).Apply(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	bodies := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")
+	if len(bodies) != 1 || !strings.Contains(bodies[0], "<Name>*.example.com.</Name>") || !strings.Contains(bodies[0], "<Name>_kdo-wildcard-cname.example.com.</Name>") {
+		t.Errorf("the wildcard record must be written with its owner record: %v", bodies)
+	}
+}
+
+func TestRoute53ApplyHonorsAdoptPolicy(t *testing.T) {
+	cases := []struct {
+		policy  string
+		owner   string
+		adopted bool
+	}{
+		{v1alpha1.AdoptPolicyNever, "", false},
+		{v1alpha1.AdoptPolicyIfUnowned, "", true},
+		{v1alpha1.AdoptPolicyIfUnowned, `"heritage=kube-dns-operator,owner=default,resource=dnsrecord/default/other"`, false},
+		{v1alpha1.AdoptPolicyAlways, `"heritage=kube-dns-operator,owner=other-cluster,resource=dnsrecord/default/www"`, true},
+		{v1alpha1.AdoptPolicyNever, ownedByWww, true},
+	}
+
+	for _, c := range cases {
+		fake := newFakeRoute53(t)
+		sets := []string{recordSetXml("www.example.com.", "CNAME", "handmade.example.com")}
+		if c.owner != "" {
+			sets = append(sets, recordSetXml("_kdo-cname.www.example.com.", "TXT", c.owner))
+		}
+		fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse(sets...)
+		record := newTestRoute53Record()
+		record.Spec.AdoptPolicy = c.policy
+
+		_, err := newTestRoute53Provider(fake).Apply(context.Background(), record)
+		adopted := len(fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")) == 1
+		if adopted != c.adopted || (!c.adopted && !hasReason(err, ReasonNotOwner)) {
+			t.Errorf("policy %s, owner %s: expected adopted=%v, got %v (%v)", c.policy, c.owner, c.adopted, adopted, err)
+		}
+	}
+}
+
+func TestRoute53DeleteHonorsOwner(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse(
+		recordSetXml("www.example.com.", "CNAME", "lb.example.com"),
+		recordSetXml("_kdo-cname.www.example.com.", "TXT", `"heritage=kube-dns-operator,owner=default,resource=dnsrecord/default/other"`))
+	provider := newTestRoute53Provider(fake)
+
+	if err := provider.Delete(context.Background(), newTestRoute53Record()); err != nil {
+		t.Fatal(err)
+	}
+	if bodies := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset"); len(bodies) != 0 {
+		t.Errorf("a record owned by somebody else must not be deleted: %v", bodies)
+	}
+
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse(
+		recordSetXml("www.example.com.", "CNAME", "lb.example.com"),
+		recordSetXml("_kdo-cname.www.example.com.", "TXT", ownedByWww))
+	if err := provider.Delete(context.Background(), newTestRoute53Record()); err != nil {
+		t.Fatal(err)
+	}
+	bodies := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")
+	if len(bodies) != 1 || strings.Count(bodies[0], "<Action>DELETE</Action>") != 2 {
+		t.Errorf("expected the record and its owner to be deleted: %v", bodies)
+	}
+}
+
+func TestRoute53DeleteRoutingSetMember(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse(
+		weightedRecordSetXml("www.example.com.", "CNAME", "eu", 10, "lb-eu.example.com"),
+		weightedRecordSetXml("www.example.com.", "CNAME", "us", 10, "lb-us.example.com"),
+		weightedRecordSetXml("_kdo-cname.www.example.com.", "TXT", "eu", 0, `"heritage=kube-dns-operator,owner=default,resource=dnsrecord/default/www-eu"`),
+		weightedRecordSetXml("_kdo-cname.www.example.com.", "TXT", "us", 0, `"heritage=kube-dns-operator,owner=default,resource=dnsrecord/default/www-us"`))
+
+	record := newTestRoute53Record()
+	record.Name = "www-eu"
+	record.Spec.Route53Records.SetIdentifier = "eu"
+	record.Spec.Route53Records.Weight = aws.Int64(10)
+	if err := newTestRoute53Provider(fake).Delete(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")
+	if len(bodies) != 1 || strings.Count(bodies[0], "<Action>DELETE</Action>") != 2 ||
+		!strings.Contains(bodies[0], "lb-eu.example.com") || !strings.Contains(bodies[0], "resource=dnsrecord/default/www-eu") {
+		t.Fatalf("expected the eu record and its owner to be deleted: %v", bodies)
+	}
+	if strings.Contains(bodies[0], "<SetIdentifier>us</SetIdentifier>") {
+		t.Errorf("the us member must be left alone: %s", bodies[0])
+	}
+}
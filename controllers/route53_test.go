@@ -2,51 +2,367 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 )
 
-func TestUpsertCNAMERoute53(t *testing.T) {
-	t.Skip("Integration test - manual ony, no resource cleanup")
-	ctx := context.Background()
-	record := v1alpha1.Route53Record{
-		AwsSecrets: v1alpha1.AwsSecret{},
-		Name:       "uuihfsdso.kube-operator-test.test.my-ideas.it",
-		Type:       "CNAME",
-		ZoneId:     os.Getenv("ZONE_ID"),
-		ResourceRecords: []string{
-			"kubeapp.dc-pilotto.my-ideas.it",
+// fakeRoute53 is a minimal Route53 stand-in: it records the request bodies and replies with canned responses
+type fakeRoute53 struct {
+	sync.Mutex
+	*httptest.Server
+	// requests the body of the requests received, keyed by "METHOD path"
+	requests map[string][]string
+	// responses the xml body returned for a "METHOD path" prefix
+	responses map[string]string
+	// statuses the http status returned for a "METHOD path" prefix, 200 if missing
+	statuses map[string]int
+	// authorizations the Authorization header of the requests received
+	authorizations []string
+}
+
+func newFakeRoute53(t *testing.T) *fakeRoute53 {
+	f := &fakeRoute53{
+		requests: map[string][]string{},
+		statuses: map[string]int{},
+		responses: map[string]string{
+			"POST /2013-04-01/hostedzone/": `<ChangeResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ChangeInfo><Id>/change/C1234</Id><Status>PENDING</Status><SubmittedAt>2022-01-01T00:00:00Z</SubmittedAt></ChangeInfo></ChangeResourceRecordSetsResponse>`,
 		},
-		Ttl:     300,
-		Comment: "INTEGTEST -- kube-dns-operator",
 	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		f.Lock()
+		defer f.Unlock()
+		body, _ := io.ReadAll(req.Body)
+		key := req.Method + " " + req.URL.Path
+		f.requests[key] = append(f.requests[key], string(body))
+		f.authorizations = append(f.authorizations, req.Header.Get("Authorization"))
+
+		// The longest matching prefix wins
+		match := ""
+		for prefix := range f.responses {
+			if strings.HasPrefix(key, prefix) && len(prefix) > len(match) {
+				match = prefix
+			}
+		}
+		if match == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		if status, hasStatus := f.statuses[match]; hasStatus {
+			w.WriteHeader(status)
+		}
+		_, _ = w.Write([]byte(f.responses[match]))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// listRecordSetsResponse returns a ListResourceRecordSets response holding the given record sets
+func listRecordSetsResponse(sets ...string) string {
+	return `<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ResourceRecordSets>` +
+		strings.Join(sets, "") +
+		`</ResourceRecordSets><IsTruncated>false</IsTruncated><MaxItems>1</MaxItems></ListResourceRecordSetsResponse>`
+}
+
+// recordSetXml returns the xml of a record set, as returned by Route53
+func recordSetXml(name, recordType string, values ...string) string {
+	xml := "<ResourceRecordSet><Name>" + name + "</Name><Type>" + recordType + "</Type><TTL>300</TTL><ResourceRecords>"
+	for _, value := range values {
+		xml += "<ResourceRecord><Value>" + value + "</Value></ResourceRecord>"
+	}
+	return xml + "</ResourceRecords></ResourceRecordSet>"
+}
+
+// weightedRecordSetXml returns the xml of a weighted record set, as returned by Route53
+func weightedRecordSetXml(name, recordType, setIdentifier string, weight int, values ...string) string {
+	xml := recordSetXml(name, recordType, values...)
+	return strings.Replace(xml, "</Type>", fmt.Sprintf("</Type><SetIdentifier>%s</SetIdentifier><Weight>%d</Weight>", setIdentifier, weight), 1)
+}
+
+// newTestRoute53Provider returns a provider calling the fake, with the credentials of newTestRoute53Record
+func newTestRoute53Provider(fake *fakeRoute53) *Route53Provider {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default"},
+		Data:       map[string][]byte{"id": []byte("AKID"), "secret": []byte("SECRET")},
+	}
+	provider := NewRoute53Provider(newTestReconciler(newMemoryProvider(), secret).Client)
+	provider.Endpoint = fake.URL
+	return provider
+}
+
+func newTestRoute53Record() *v1alpha1.DnsRecord {
+	record := newTestRecord()
+	record.Spec.Route53Records.AwsSecrets = v1alpha1.AwsSecret{SecretName: "aws", AccessKeyIDKey: "id", SecretAccessKeyKey: "secret"}
+	return record
+}
+
+func (f *fakeRoute53) bodies(key string) []string {
+	f.Lock()
+	defer f.Unlock()
+	return f.requests[key]
+}
 
-	err := UpsertRoute53(ctx, record, ActionUpsert, os.Getenv("AWS_ACCESS_KEY"), os.Getenv("AWS_ACCESS_SECRET"))
+// newTestRoute53Client returns a client calling the fake
+func newTestRoute53Client(t *testing.T, fake *fakeRoute53) *route53.Client {
+	t.Helper()
+	svc, err := newTestRoute53Provider(fake).route53Client(context.Background(), newTestRoute53Record())
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
+	return svc
 }
 
-func TestUpsertARoute53(t *testing.T) {
-	t.Skip("Integration test - manual ony, no resource cleanup")
-	ctx := context.Background()
+// applyTestRoute53Record applies the record with a provider calling the fake, the record does not exist yet
+func applyTestRoute53Record(t *testing.T, fake *fakeRoute53, record v1alpha1.Route53Record) error {
+	t.Helper()
+	fake.responses["GET /2013-04-01/hostedzone/"+record.ZoneId+"/rrset"] = listRecordSetsResponse()
+	dnsRecord := newTestRoute53Record()
+	record.AwsSecrets = dnsRecord.Spec.Route53Records.AwsSecrets
+	dnsRecord.Spec.Route53Records = record
+	_, err := newTestRoute53Provider(fake).Apply(context.Background(), dnsRecord)
+	return err
+}
+
+func TestRoute53ApplyHonorsTtlAndEndpoint(t *testing.T) {
+	fake := newFakeRoute53(t)
+
 	record := v1alpha1.Route53Record{
-		AwsSecrets: v1alpha1.AwsSecret{},
-		Name:       "uuihfsdso-a.kube-operator-test.test.my-ideas.it",
-		Type:       "A",
-		ZoneId:     os.Getenv("ZONE_ID"),
-		ResourceRecords: []string{
-			"151.100.152.223",
-		},
-		Ttl:     300,
-		Comment: "INTEGTEST -- kube-dns-operator",
+		Name:            "www.example.com",
+		Type:            "CNAME",
+		ZoneId:          "Z123",
+		ResourceRecords: []string{"lb.example.com"},
+		Ttl:             60,
+	}
+	if err := applyTestRoute53Record(t, fake, record); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 call to the custom endpoint, got %d", len(bodies))
+	}
+	if !strings.Contains(bodies[0], "<TTL>60</TTL>") {
+		t.Errorf("ttl not honored: %s", bodies[0])
+	}
+}
+
+func TestRoute53ApplyDefaultsTtl(t *testing.T) {
+	fake := newFakeRoute53(t)
+
+	record := v1alpha1.Route53Record{Name: "www.example.com", Type: "A", ZoneId: "Z123", ResourceRecords: []string{"10.0.0.1"}}
+	if err := applyTestRoute53Record(t, fake, record); err != nil {
+		t.Fatal(err)
+	}
+	if body := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")[0]; !strings.Contains(body, "<TTL>300</TTL>") {
+		t.Errorf("expected the default ttl: %s", body)
+	}
+
+	record.Ttl = -1
+	if err := applyTestRoute53Record(t, fake, record); err == nil {
+		t.Error("a negative ttl must be rejected")
+	}
+}
+
+func TestRoute53ApplyAlias(t *testing.T) {
+	fake := newFakeRoute53(t)
+
+	record := v1alpha1.Route53Record{
+		Name:   "example.com",
+		Type:   "A",
+		ZoneId: "Z123",
+		Ttl:    300,
+		Alias:  &v1alpha1.Route53Alias{DNSName: "lb-1.eu-west-1.elb.amazonaws.com", HostedZoneId: "Z32O12XQLNTSW2", EvaluateTargetHealth: true},
+	}
+	if err := applyTestRoute53Record(t, fake, record); err != nil {
+		t.Fatal(err)
+	}
+	body := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")[0]
+	if !strings.Contains(body, "<AliasTarget><DNSName>lb-1.eu-west-1.elb.amazonaws.com</DNSName><EvaluateTargetHealth>true</EvaluateTargetHealth><HostedZoneId>Z32O12XQLNTSW2</HostedZoneId></AliasTarget>") {
+		t.Errorf("alias target not sent: %s", body)
+	}
+	// The first change is the alias, the second one its owner TXT record
+	alias := body[:strings.Index(body, "</Change>")]
+	if strings.Contains(alias, "<TTL>") || strings.Contains(alias, "<ResourceRecords>") {
+		t.Errorf("alias records have neither ttl nor values: %s", body)
 	}
 
-	err := UpsertRoute53(ctx, record, ActionUpsert, os.Getenv("AWS_ACCESS_KEY"), os.Getenv("AWS_ACCESS_SECRET"))
+	record.ResourceRecords = []string{"10.0.0.1"}
+	if err := applyTestRoute53Record(t, fake, record); !hasReason(err, ReasonInvalidRecord) {
+		t.Errorf("alias and resourceRecords must be mutually exclusive, got %v", err)
+	}
+	if calls := len(fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")); calls != 1 {
+		t.Errorf("an invalid record must not be sent to Route53, got %d calls", calls)
+	}
+}
+
+func TestRoute53ApplyRoutingPolicy(t *testing.T) {
+	fake := newFakeRoute53(t)
+
+	record := v1alpha1.Route53Record{Name: "www.example.com", Type: "A", ZoneId: "Z123", ResourceRecords: []string{"10.0.0.1"}}
+	record.SetIdentifier = "eu-primary"
+	record.Failover = v1alpha1.FailoverPrimary
+	record.HealthCheckId = "hc-1"
+	if err := applyTestRoute53Record(t, fake, record); err != nil {
+		t.Fatal(err)
+	}
+	body := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")[0]
+	for _, expected := range []string{"<SetIdentifier>eu-primary</SetIdentifier>", "<Failover>PRIMARY</Failover>", "<HealthCheckId>hc-1</HealthCheckId>"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("%s not sent: %s", expected, body)
+		}
+	}
+
+	invalid := []v1alpha1.Route53Routing{
+		{Weight: aws.Int64(10)},
+		{SetIdentifier: "eu"},
+		{SetIdentifier: "eu", Weight: aws.Int64(10), Region: "eu-west-1"},
+	}
+	for _, routing := range invalid {
+		record.Route53Routing = routing
+		if err := applyTestRoute53Record(t, fake, record); !hasReason(err, ReasonInvalidRecord) {
+			t.Errorf("%+v: expected an invalid record, got %v", routing, err)
+		}
+	}
+}
+
+func TestGetRecordRoute53MatchesSetIdentifier(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse(
+		weightedRecordSetXml("www.example.com.", "A", "eu", 10, "10.0.0.1"),
+		weightedRecordSetXml("www.example.com.", "A", "us", 20, "10.0.1.1"))
+	svc := newTestRoute53Client(t, fake)
+
+	record := v1alpha1.Route53Record{Name: "www.example.com", Type: "A", ZoneId: "Z123"}
+	record.SetIdentifier = "us"
+	current, err := GetRecordRoute53(ctx, svc, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current == nil || current.Values[0] != "10.0.1.1" || aws.ToInt64(current.Routing.Weight) != 20 {
+		t.Errorf("unexpected record %+v", current)
+	}
+}
+
+func TestRoute53ChangeStatus(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse()
+	fake.responses["GET /2013-04-01/change/"] = `<GetChangeResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ChangeInfo><Id>/change/C1234</Id><Status>INSYNC</Status><SubmittedAt>2022-01-01T00:00:00Z</SubmittedAt></ChangeInfo></GetChangeResponse>`
+	provider := newTestRoute53Provider(fake)
+	record := newTestRoute53Record()
+
+	changeId, err := provider.Apply(ctx, record)
 	if err != nil {
+		t.Fatal(err)
+	}
+
+	inSync, err := provider.ChangeStatus(ctx, record, changeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inSync {
+		t.Error("the change should be in sync")
+	}
+	if len(fake.bodies("GET /2013-04-01/change/C1234")) != 1 {
+		t.Errorf("change C1234 not polled: %v", fake.requests)
+	}
+}
+
+func TestGetRecordRoute53(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = `<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><ResourceRecordSets><ResourceRecordSet><Name>\052.example.com.</Name><Type>A</Type><TTL>60</TTL><ResourceRecords><ResourceRecord><Value>10.0.0.1</Value></ResourceRecord></ResourceRecords></ResourceRecordSet></ResourceRecordSets><IsTruncated>false</IsTruncated><MaxItems>1</MaxItems></ListResourceRecordSetsResponse>`
+	svc := newTestRoute53Client(t, fake)
+
+	current, err := GetRecordRoute53(ctx, svc, v1alpha1.Route53Record{Name: "*.example.com", Type: "A", ZoneId: "Z123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current == nil || current.Ttl != 60 || current.Values[0] != "10.0.0.1" {
+		t.Errorf("unexpected record %+v", current)
+	}
+
+	missing, err := GetRecordRoute53(ctx, svc, v1alpha1.Route53Record{Name: "www.example.com", Type: "A", ZoneId: "Z123"})
+	if err != nil || missing != nil {
+		t.Errorf("expected no record, got %+v %v", missing, err)
+	}
+}
+
+func TestRoute53ApplyDeletesRenamedRecord(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzone/Z123/rrset"] = listRecordSetsResponse(recordSetXml("old.example.com.", "CNAME", "edited.example.com"))
+
+	record := newTestRoute53Record()
+	record.Status.AppliedRecord = &v1alpha1.AppliedRecord{ZoneId: "Z123", Name: "old.example.com", Type: "CNAME", Ttl: 300, ResourceRecords: []string{"lb.example.com"}}
+	provider := newTestRoute53Provider(fake)
+
+	if _, err := provider.Apply(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")
+	if len(bodies) != 1 {
+		t.Fatalf("expected a single change batch, got %d", len(bodies))
+	}
+	deleteAt := strings.Index(bodies[0], "<Action>DELETE</Action><ResourceRecordSet><Name>old.example.com.</Name>")
+	upsertAt := strings.Index(bodies[0], "<Action>UPSERT</Action><ResourceRecordSet><Name>www.example.com</Name>")
+	if deleteAt < 0 || upsertAt < deleteAt {
+		t.Errorf("expected the delete of the old record before the upsert: %s", bodies[0])
+	}
+	if !strings.Contains(bodies[0], "<Value>edited.example.com</Value>") {
+		t.Errorf("the old record must be deleted with its live values: %s", bodies[0])
+	}
+}
+
+// newIntegrationRoute53Provider returns a provider using the access keys in the environment
+func newIntegrationRoute53Provider() *Route53Provider {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default"},
+		Data:       map[string][]byte{"id": []byte(os.Getenv("AWS_ACCESS_KEY")), "secret": []byte(os.Getenv("AWS_ACCESS_SECRET"))},
+	}
+	return NewRoute53Provider(newTestReconciler(newMemoryProvider(), secret).Client)
+}
+
+func TestUpsertCNAMERoute53(t *testing.T) {
+	t.Skip("Integration test - manual ony, no resource cleanup")
+	ctx := context.Background()
+	record := newTestRoute53Record()
+	record.Spec.Route53Records.Name = "uuihfsdso.kube-operator-test.test.my-ideas.it"
+	record.Spec.Route53Records.Type = "CNAME"
+	record.Spec.Route53Records.ZoneId = os.Getenv("ZONE_ID")
+	record.Spec.Route53Records.ResourceRecords = []string{"kubeapp.dc-pilotto.my-ideas.it"}
+	record.Spec.Route53Records.Comment = "INTEGTEST -- kube-dns-operator"
+
+	if _, err := newIntegrationRoute53Provider().Apply(ctx, record); err != nil {
 		t.Error(err)
 	}
+}
 
+func TestUpsertARoute53(t *testing.T) {
+	t.Skip("Integration test - manual ony, no resource cleanup")
+	ctx := context.Background()
+	record := newTestRoute53Record()
+	record.Spec.Route53Records.Name = "uuihfsdso-a.kube-operator-test.test.my-ideas.it"
+	record.Spec.Route53Records.Type = "A"
+	record.Spec.Route53Records.ZoneId = os.Getenv("ZONE_ID")
+	record.Spec.Route53Records.ResourceRecords = []string{"151.100.152.223"}
+	record.Spec.Route53Records.Comment = "INTEGTEST -- kube-dns-operator"
+
+	if _, err := newIntegrationRoute53Provider().Apply(ctx, record); err != nil {
+		t.Error(err)
+	}
 }
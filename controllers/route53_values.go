@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"strings"
+)
+
+// txtChunkSize the maximum length of a character-string in a TXT record
+const txtChunkSize = 255
+
+// route53Values renders the typed values in the Route53 resource record syntax
+func route53Values(values netv1alpha1.StructuredValues) []string {
+	var rendered []string
+	for _, mx := range values.MX {
+		rendered = append(rendered, fmt.Sprintf("%d %s", mx.Priority, mx.Host))
+	}
+	for _, srv := range values.SRV {
+		rendered = append(rendered, fmt.Sprintf("%d %d %d %s", srv.Priority, srv.Weight, srv.Port, srv.Target))
+	}
+	for _, caa := range values.CAA {
+		rendered = append(rendered, fmt.Sprintf("%d %s %s", caa.Flags, caa.Tag, quoteTxt(caa.Value)))
+	}
+	for _, txt := range values.TXT {
+		rendered = append(rendered, txtValue(txt))
+	}
+	for _, naptr := range values.NAPTR {
+		replacement := naptr.Replacement
+		if replacement == "" {
+			replacement = "."
+		}
+		rendered = append(rendered, fmt.Sprintf("%d %d %s %s %s %s", naptr.Order, naptr.Preference,
+			quoteTxt(naptr.Flags), quoteTxt(naptr.Service), quoteTxt(naptr.Regexp), replacement))
+	}
+	return rendered
+}
+
+// txtValue splits a text longer than 255 bytes in several quoted strings, that the resolvers concatenate
+func txtValue(text string) string {
+	if text == "" {
+		return `""`
+	}
+	var chunks []string
+	for len(text) > txtChunkSize {
+		chunks = append(chunks, quoteTxt(text[:txtChunkSize]))
+		text = text[txtChunkSize:]
+	}
+	if text != "" {
+		chunks = append(chunks, quoteTxt(text))
+	}
+	return strings.Join(chunks, " ")
+}
+
+// quoteTxt quotes a character-string, escaping the quotes and the backslashes,
+// and the bytes that are not printable ASCII in the octal \ooo format of Route53
+func quoteTxt(text string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"' || c == '\\':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&quoted, "\\%03o", c)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoute53Values(t *testing.T) {
+	values := route53Values(v1alpha1.StructuredValues{
+		MX:    []v1alpha1.MXValue{{Priority: 10, Host: "mail.example.com."}},
+		SRV:   []v1alpha1.SRVValue{{Priority: 1, Weight: 10, Port: 5060, Target: "sip.example.com."}},
+		CAA:   []v1alpha1.CAAValue{{Flags: 128, Tag: "issue", Value: "letsencrypt.org"}},
+		TXT:   []string{`say "hi" \o/`, "caffè"},
+		NAPTR: []v1alpha1.NAPTRValue{{Order: 100, Preference: 10, Flags: "S", Service: "SIP+D2U", Replacement: "_sip._udp.example.com."}},
+	})
+	expected := []string{
+		"10 mail.example.com.",
+		"1 10 5060 sip.example.com.",
+		`128 issue "letsencrypt.org"`,
+		`"say \"hi\" \\o/"`,
+		`"caff\303\250"`,
+		`100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`,
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %q, got %q", expected, values)
+	}
+}
+
+func TestTxtValueSplitsLongTexts(t *testing.T) {
+	text := strings.Repeat("a", 255) + strings.Repeat("b", 255) + "c"
+	expected := `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("b", 255) + `" "c"`
+	if value := txtValue(text); value != expected {
+		t.Errorf("expected %s, got %s", expected, value)
+	}
+	if value := txtValue(""); value != `""` {
+		t.Errorf("expected an empty string, got %s", value)
+	}
+}
+
+func TestRoute53ApplyStructuredValues(t *testing.T) {
+	fake := newFakeRoute53(t)
+
+	record := v1alpha1.Route53Record{Name: "example.com", Type: "MX", ZoneId: "Z123", Ttl: 300}
+	record.MX = []v1alpha1.MXValue{{Priority: 10, Host: "mail1.example.com"}, {Priority: 20, Host: "mail2.example.com"}}
+	if err := applyTestRoute53Record(t, fake, record); err != nil {
+		t.Fatal(err)
+	}
+	body := fake.bodies("POST /2013-04-01/hostedzone/Z123/rrset")[0]
+	if !strings.Contains(body, "<ResourceRecord><Value>10 mail1.example.com</Value></ResourceRecord><ResourceRecord><Value>20 mail2.example.com</Value></ResourceRecord>") {
+		t.Errorf("mx values not rendered: %s", body)
+	}
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReasonAmbiguousZone several hosted zones match the record name and the zone preference
+const ReasonAmbiguousZone = "AmbiguousZone"
+
+// zoneCacheTtl how long the hosted zones found by name are cached
+const zoneCacheTtl = 5 * time.Minute
+
+// hostedZone a Route53 hosted zone
+type hostedZone struct {
+	Id      string
+	Name    string
+	Private bool
+}
+
+// zoneCache caches the hosted zones by AWS account and zone name or id
+type zoneCache struct {
+	sync.Mutex
+	entries map[string]zoneCacheEntry
+}
+
+type zoneCacheEntry struct {
+	zones   []hostedZone
+	expires time.Time
+}
+
+func newZoneCache() *zoneCache {
+	return &zoneCache{entries: map[string]zoneCacheEntry{}}
+}
+
+// zonesNamed returns the hosted zones with the given name. account identifies the credentials used by svc
+func (c *zoneCache) zonesNamed(ctx context.Context, svc *route53.Client, account, name string) ([]hostedZone, error) {
+	key := account + "|" + name
+	c.Lock()
+	entry, found := c.entries[key]
+	c.Unlock()
+	if found && time.Now().Before(entry.expires) {
+		return entry.zones, nil
+	}
+
+	zones, err := listHostedZonesNamed(ctx, svc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	c.entries[key] = zoneCacheEntry{zones: zones, expires: time.Now().Add(zoneCacheTtl)}
+	c.Unlock()
+	return zones, nil
+}
+
+// zoneWithId returns the hosted zone with the given id. account identifies the credentials used by svc
+func (c *zoneCache) zoneWithId(ctx context.Context, svc *route53.Client, account, zoneId string) (hostedZone, error) {
+	key := account + "|id:" + zoneId
+	c.Lock()
+	entry, found := c.entries[key]
+	c.Unlock()
+	if found && time.Now().Before(entry.expires) {
+		return entry.zones[0], nil
+	}
+
+	output, err := svc.GetHostedZone(ctx, &route53.GetHostedZoneInput{Id: aws.String(zoneId)})
+	if err != nil {
+		return hostedZone{}, classifyRoute53Error(err)
+	}
+	zone := hostedZone{
+		Id:      zoneId,
+		Name:    normalizeDnsName(aws.ToString(output.HostedZone.Name)),
+		Private: output.HostedZone.Config != nil && output.HostedZone.Config.PrivateZone,
+	}
+
+	c.Lock()
+	c.entries[key] = zoneCacheEntry{zones: []hostedZone{zone}, expires: time.Now().Add(zoneCacheTtl)}
+	c.Unlock()
+	return zone, nil
+}
+
+// listHostedZonesNamed returns the hosted zones with the given name.
+// The zones are listed by name, so the zones with the same name are consecutive
+func listHostedZonesNamed(ctx context.Context, svc *route53.Client, name string) ([]hostedZone, error) {
+	var zones []hostedZone
+	input := &route53.ListHostedZonesByNameInput{DNSName: aws.String(name)}
+	for {
+		output, err := svc.ListHostedZonesByName(ctx, input)
+		if err != nil {
+			return nil, classifyRoute53Error(err)
+		}
+
+		for _, zone := range output.HostedZones {
+			if normalizeDnsName(aws.ToString(zone.Name)) != name {
+				return zones, nil
+			}
+			zones = append(zones, hostedZone{
+				Id:      strings.TrimPrefix(aws.ToString(zone.Id), "/hostedzone/"),
+				Name:    name,
+				Private: zone.Config != nil && zone.Config.PrivateZone,
+			})
+		}
+
+		if !output.IsTruncated {
+			return zones, nil
+		}
+		input.DNSName = output.NextDNSName
+		input.HostedZoneId = output.NextHostedZoneId
+	}
+}
+
+// findHostedZone returns the id of the hosted zone with the longest suffix match on the record name.
+// When both public and private zones match, the preference decides; several zones left are an error
+func findHostedZone(ctx context.Context, svc *route53.Client, cache *zoneCache, account string, record v1alpha1.Route53Record) (string, error) {
+	labels := strings.Split(normalizeDnsName(record.Name), ".")
+	for i := range labels {
+		// A wildcard is never the name of a zone
+		if labels[i] == "*" {
+			continue
+		}
+		name := strings.Join(labels[i:], ".")
+		zones, err := cache.zonesNamed(ctx, svc, account, name)
+		if err != nil {
+			return "", err
+		}
+		if len(zones) == 0 {
+			continue
+		}
+
+		preferred := preferredZones(zones, record.ZonePreference)
+		if len(preferred) > 1 {
+			var ids []string
+			for _, zone := range preferred {
+				ids = append(ids, zone.Id)
+			}
+			return "", NewTerminalError(ReasonAmbiguousZone, fmt.Errorf("several hosted zones named %s: %s, set zoneId", name, strings.Join(ids, ", ")))
+		}
+		log.FromContext(ctx).Info("hosted zone found", "zone", name, "zoneId", preferred[0].Id)
+		return preferred[0].Id, nil
+	}
+
+	return "", NewTerminalError(ReasonZoneNotFound, fmt.Errorf("no hosted zone matches %s", record.Name))
+}
+
+// preferredZones returns the zones with the preferred visibility, or all the zones if none has it
+func preferredZones(zones []hostedZone, preference string) []hostedZone {
+	private := preference == v1alpha1.ZonePreferencePrivate
+	var preferred []hostedZone
+	for _, zone := range zones {
+		if zone.Private == private {
+			preferred = append(preferred, zone)
+		}
+	}
+	if len(preferred) == 0 {
+		return zones
+	}
+	return preferred
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"strings"
+	"testing"
+)
+
+func hostedZonesResponse(zones ...string) string {
+	return `<ListHostedZonesByNameResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"><HostedZones>` +
+		strings.Join(zones, "") +
+		`</HostedZones><IsTruncated>false</IsTruncated><MaxItems>100</MaxItems></ListHostedZonesByNameResponse>`
+}
+
+func hostedZoneXml(id, name string, private bool) string {
+	privateZone := "false"
+	if private {
+		privateZone = "true"
+	}
+	return `<HostedZone><Id>/hostedzone/` + id + `</Id><Name>` + name + `</Name><CallerReference>ref</CallerReference><Config><PrivateZone>` + privateZone + `</PrivateZone></Config></HostedZone>`
+}
+
+func TestFindHostedZone(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	// The fake ignores the name filter: the zones are listed from the longest matching name, as Route53 does
+	fake.responses["GET /2013-04-01/hostedzonesbyname"] = hostedZonesResponse(
+		hostedZoneXml("ZPUBLIC", "example.com.", false),
+		hostedZoneXml("ZPRIVATE", "example.com.", true),
+		hostedZoneXml("ZOTHER", "example.org.", false))
+	svc := newTestRoute53Client(t, fake)
+	cache := newZoneCache()
+
+	cases := []struct {
+		name       string
+		preference string
+		zoneId     string
+	}{
+		{"www.example.com", "", "ZPUBLIC"},
+		{"*.www.example.com", v1alpha1.ZonePreferencePrivate, "ZPRIVATE"},
+		{"example.com", v1alpha1.ZonePreferencePublic, "ZPUBLIC"},
+	}
+	for _, c := range cases {
+		zoneId, err := findHostedZone(ctx, svc, cache, "account", v1alpha1.Route53Record{Name: c.name, ZonePreference: c.preference})
+		if err != nil || zoneId != c.zoneId {
+			t.Errorf("%s %s: expected %s, got %s %v", c.name, c.preference, c.zoneId, zoneId, err)
+		}
+	}
+
+	// The zones are cached by name
+	calls := len(fake.bodies("GET /2013-04-01/hostedzonesbyname"))
+	if _, err := findHostedZone(ctx, svc, cache, "account", v1alpha1.Route53Record{Name: "api.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if again := len(fake.bodies("GET /2013-04-01/hostedzonesbyname")); again != calls+1 {
+		t.Errorf("expected a single call for the new name api.example.com, got %d", again-calls)
+	}
+}
+
+func TestFindHostedZoneReportsAmbiguousZones(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzonesbyname"] = hostedZonesResponse(
+		hostedZoneXml("ZPRIVATE1", "example.com.", true),
+		hostedZoneXml("ZPRIVATE2", "example.com.", true))
+	svc := newTestRoute53Client(t, fake)
+
+	_, err := findHostedZone(ctx, svc, newZoneCache(), "account", v1alpha1.Route53Record{Name: "www.example.com"})
+	if !hasReason(err, ReasonAmbiguousZone) {
+		t.Errorf("expected an ambiguous zone, got %v", err)
+	}
+
+	fake.responses["GET /2013-04-01/hostedzonesbyname"] = hostedZonesResponse(hostedZoneXml("ZOTHER", "example.org.", false))
+	_, err = findHostedZone(ctx, svc, newZoneCache(), "account", v1alpha1.Route53Record{Name: "www.example.com"})
+	if !hasReason(err, ReasonZoneNotFound) {
+		t.Errorf("expected no zone, got %v", err)
+	}
+}
+
+func TestRoute53ResolveSetsZoneId(t *testing.T) {
+	fake := newFakeRoute53(t)
+	fake.responses["GET /2013-04-01/hostedzonesbyname"] = hostedZonesResponse(hostedZoneXml("ZFOUND", "example.com.", false))
+	record := newTestRoute53Record()
+	record.Spec.Route53Records.ZoneId = ""
+
+	if err := newTestRoute53Provider(fake).Resolve(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Spec.Route53Records.ZoneId != "ZFOUND" {
+		t.Errorf("zone not resolved: %s", record.Spec.Route53Records.ZoneId)
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"time"
+)
+
+// Route53HealthCheckReconciler reconciles a Route53HealthCheck object
+type Route53HealthCheckReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Route53 provides the AWS credentials and defaults
+	Route53 *Route53Provider
+}
+
+const healthCheckFinalizer = "route53healthcheck.net.beekube.cloud/finalizer"
+
+// ReasonChildNotReady a child of a CALCULATED health check has not been created yet
+const ReasonChildNotReady = "ChildNotReady"
+
+// +kubebuilder:rbac:groups=net.beekube.cloud,resources=route53healthchecks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=net.beekube.cloud,resources=route53healthchecks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=net.beekube.cloud,resources=route53healthchecks/finalizers,verbs=update
+
+func (r *Route53HealthCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	healthCheck := &netv1alpha1.Route53HealthCheck{}
+	if err := r.Get(ctx, req.NamespacedName, healthCheck); err != nil {
+		if errors.IsNotFound(err) {
+			return DoNotRequeue()
+		}
+		return RequeueWithError(err)
+	}
+	spec := healthCheck.Spec
+
+	// Resource Delete
+	if healthCheck.GetDeletionTimestamp() != nil {
+		if !controllerutil.ContainsFinalizer(healthCheck, healthCheckFinalizer) {
+			return DoNotRequeue()
+		}
+		if err := r.deleteHealthCheck(ctx, healthCheck); err != nil {
+			// Even the terminal errors are retried: the finalizer can only be removed once the health check is gone
+			_, _ = r.handleError(ctx, healthCheck, err)
+			return RequeueWithError(err)
+		}
+		controllerutil.RemoveFinalizer(healthCheck, healthCheckFinalizer)
+		return ctrl.Result{}, r.Update(ctx, healthCheck)
+	}
+
+	source := r.Route53.credentialSource(healthCheck.Namespace, spec.AwsSecrets, spec.RoleArn, spec.ExternalId, spec.AwsRegion, spec.AwsEndpoint)
+	svc, err := r.Route53.clientFor(ctx, source)
+	if err != nil {
+		return r.handleError(ctx, healthCheck, err)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return r.handleError(ctx, healthCheck, NewTerminalError(ReasonInvalidRecord, err))
+	}
+
+	// Nothing changed since the last successful reconciliation
+	if healthCheck.Status.HealthCheckId != "" &&
+		healthCheck.Status.ObservedGeneration == healthCheck.Generation &&
+		meta.IsStatusConditionTrue(healthCheck.Status.Conditions, netv1alpha1.ConditionReady) {
+		return DoNotRequeue()
+	}
+
+	children, err := r.childHealthCheckIds(ctx, healthCheck)
+	if err != nil {
+		return r.handleError(ctx, healthCheck, err)
+	}
+	config := healthCheckConfig(spec, children)
+
+	// The finalizer is added before creating the health check, so that it can't be leaked
+	if !controllerutil.ContainsFinalizer(healthCheck, healthCheckFinalizer) {
+		controllerutil.AddFinalizer(healthCheck, healthCheckFinalizer)
+		if err := r.Update(ctx, healthCheck); err != nil {
+			return RequeueWithError(err)
+		}
+	}
+
+	id := healthCheck.Status.HealthCheckId
+	if id != "" {
+		err := UpdateHealthCheckRoute53(ctx, svc, id, config)
+		if hasReason(err, ReasonHealthCheckNotFound) {
+			logger.Info("health check deleted outside the operator, it will be created again", "healthCheckId", id)
+			id = ""
+		} else if err != nil {
+			return r.handleError(ctx, healthCheck, err)
+		}
+	}
+
+	if id == "" {
+		// The caller reference makes the creation idempotent if the status update below fails.
+		// Route53 does not accept the caller reference of a deleted health check
+		callerReference := fmt.Sprintf("%s-%d", healthCheck.UID, healthCheck.Generation)
+		if healthCheck.Status.HealthCheckId != "" {
+			callerReference = fmt.Sprintf("%s-%d", callerReference, time.Now().Unix())
+		}
+		id, err = CreateHealthCheckRoute53(ctx, svc, callerReference, config)
+		if err != nil {
+			return r.handleError(ctx, healthCheck, err)
+		}
+		healthCheck.Status.HealthCheckId = id
+	}
+
+	healthCheck.Status.ObservedGeneration = healthCheck.Generation
+	meta.SetStatusCondition(&healthCheck.Status.Conditions, metav1.Condition{
+		Type:               netv1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonApplied,
+		Message:            "Health check " + healthCheck.Status.HealthCheckId + " applied to Route53",
+		ObservedGeneration: healthCheck.Generation,
+	})
+	return ctrl.Result{}, r.Status().Update(ctx, healthCheck)
+}
+
+// deleteHealthCheck deletes the health check from Route53. The credentials are not needed
+// if it has never been created
+func (r *Route53HealthCheckReconciler) deleteHealthCheck(ctx context.Context, healthCheck *netv1alpha1.Route53HealthCheck) error {
+	if healthCheck.Status.HealthCheckId == "" {
+		return nil
+	}
+
+	spec := healthCheck.Spec
+	source := r.Route53.credentialSource(healthCheck.Namespace, spec.AwsSecrets, spec.RoleArn, spec.ExternalId, spec.AwsRegion, spec.AwsEndpoint)
+	svc, err := r.Route53.clientFor(ctx, source)
+	if err != nil {
+		return err
+	}
+	return DeleteHealthCheckRoute53(ctx, svc, healthCheck.Status.HealthCheckId)
+}
+
+// childHealthCheckIds returns the Route53 ids of the children of a CALCULATED health check
+func (r *Route53HealthCheckReconciler) childHealthCheckIds(ctx context.Context, healthCheck *netv1alpha1.Route53HealthCheck) ([]string, error) {
+	var ids []string
+	for _, name := range healthCheck.Spec.ChildHealthChecks {
+		child := &netv1alpha1.Route53HealthCheck{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: healthCheck.Namespace, Name: name}, child); err != nil {
+			return nil, NewRetryableError(ReasonChildNotReady, fmt.Errorf("can't get the child health check %s: %w", name, err))
+		}
+		if child.Status.HealthCheckId == "" {
+			return nil, NewRetryableError(ReasonChildNotReady, fmt.Errorf("the child health check %s has not been created yet", name))
+		}
+		ids = append(ids, child.Status.HealthCheckId)
+	}
+	return ids, nil
+}
+
+// handleError records the error in the Ready condition. Retryable errors are retried by the controller
+// with its rate limiter, terminal errors are not retried until the health check changes
+func (r *Route53HealthCheckReconciler) handleError(ctx context.Context, healthCheck *netv1alpha1.Route53HealthCheck, err error) (ctrl.Result, error) {
+	providerError := ClassifyError(err)
+	log.FromContext(ctx).Error(err, "can't reconcile the health check", "reason", providerError.Reason)
+
+	meta.SetStatusCondition(&healthCheck.Status.Conditions, metav1.Condition{
+		Type:               netv1alpha1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             providerError.Reason,
+		Message:            err.Error(),
+		ObservedGeneration: healthCheck.Generation,
+	})
+	if errStatus := r.Status().Update(ctx, healthCheck); errStatus != nil {
+		log.FromContext(ctx).Error(errStatus, "can't update the Route53HealthCheck status")
+	}
+
+	if !providerError.Retryable {
+		return DoNotRequeue()
+	}
+	return RequeueWithError(err)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Route53HealthCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&netv1alpha1.Route53HealthCheck{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
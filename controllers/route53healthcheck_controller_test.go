@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strings"
+	"testing"
+)
+
+const healthCheckXml = `<HealthCheck><Id>HC1</Id><CallerReference>ref</CallerReference><HealthCheckConfig><Type>HTTPS</Type><RequestInterval>30</RequestInterval></HealthCheckConfig><HealthCheckVersion>1</HealthCheckVersion></HealthCheck>`
+
+func newFakeRoute53HealthChecks(t *testing.T) *fakeRoute53 {
+	fake := newFakeRoute53(t)
+	fake.responses["POST /2013-04-01/healthcheck"] = `<CreateHealthCheckResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` + healthCheckXml + `</CreateHealthCheckResponse>`
+	fake.statuses["POST /2013-04-01/healthcheck"] = 201
+	fake.responses["GET /2013-04-01/healthcheck/HC1"] = `<GetHealthCheckResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` + healthCheckXml + `</GetHealthCheckResponse>`
+	fake.responses["POST /2013-04-01/healthcheck/HC1"] = `<UpdateHealthCheckResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">` + healthCheckXml + `</UpdateHealthCheckResponse>`
+	fake.responses["DELETE /2013-04-01/healthcheck/HC1"] = `<DeleteHealthCheckResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/"></DeleteHealthCheckResponse>`
+	return fake
+}
+
+func newTestHealthCheck() *netv1alpha1.Route53HealthCheck {
+	return &netv1alpha1.Route53HealthCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "www", Namespace: "default", UID: "uid-1"},
+		Spec: netv1alpha1.Route53HealthCheckSpec{
+			AwsSecrets:       netv1alpha1.AwsSecret{SecretName: "aws", AccessKeyIDKey: "id", SecretAccessKeyKey: "secret"},
+			Type:             netv1alpha1.HealthCheckHttps,
+			Fqdn:             "www.example.com",
+			Path:             "/healthz",
+			RequestInterval:  30,
+			FailureThreshold: 3,
+		},
+	}
+}
+
+func newTestHealthCheckReconciler(fake *fakeRoute53, objs ...client.Object) *Route53HealthCheckReconciler {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default"},
+		Data:       map[string][]byte{"id": []byte("AKID"), "secret": []byte("SECRET")},
+	}
+	dnsReconciler := newTestReconciler(newMemoryProvider(), append(objs, secret)...)
+	provider := NewRoute53Provider(dnsReconciler.Client)
+	provider.Endpoint = fake.URL
+	return &Route53HealthCheckReconciler{Client: dnsReconciler.Client, Scheme: dnsReconciler.Scheme, Route53: provider}
+}
+
+func reconcileHealthCheck(t *testing.T, r *Route53HealthCheckReconciler, healthCheck *netv1alpha1.Route53HealthCheck) *netv1alpha1.Route53HealthCheck {
+	t.Helper()
+	key := client.ObjectKeyFromObject(healthCheck)
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	updated := &netv1alpha1.Route53HealthCheck{}
+	if err := r.Get(context.Background(), key, updated); err != nil {
+		t.Fatal(err)
+	}
+	return updated
+}
+
+func TestReconcileHealthCheckLifecycle(t *testing.T) {
+	fake := newFakeRoute53HealthChecks(t)
+	healthCheck := newTestHealthCheck()
+	r := newTestHealthCheckReconciler(fake, healthCheck)
+
+	updated := reconcileHealthCheck(t, r, healthCheck)
+	if updated.Status.HealthCheckId != "HC1" || !meta.IsStatusConditionTrue(updated.Status.Conditions, netv1alpha1.ConditionReady) {
+		t.Fatalf("health check not created: %+v", updated.Status)
+	}
+	if !controllerutil.ContainsFinalizer(updated, healthCheckFinalizer) {
+		t.Error("finalizer not added")
+	}
+	created := fake.bodies("POST /2013-04-01/healthcheck")
+	if len(created) != 1 || !strings.Contains(created[0], "<CallerReference>uid-1-0</CallerReference>") || !strings.Contains(created[0], "<ResourcePath>/healthz</ResourcePath>") {
+		t.Errorf("unexpected create request: %v", created)
+	}
+
+	// Nothing changed: Route53 is not called again
+	reconcileHealthCheck(t, r, healthCheck)
+	if len(fake.bodies("POST /2013-04-01/healthcheck")) != 1 || len(fake.bodies("POST /2013-04-01/healthcheck/HC1")) != 0 {
+		t.Error("an unchanged health check must not be sent to Route53")
+	}
+
+	updated.Spec.FailureThreshold = 5
+	updated.Generation = 2
+	if err := r.Update(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	reconcileHealthCheck(t, r, healthCheck)
+	if bodies := fake.bodies("POST /2013-04-01/healthcheck/HC1"); len(bodies) != 1 || !strings.Contains(bodies[0], "<FailureThreshold>5</FailureThreshold>") {
+		t.Errorf("unexpected update request: %v", bodies)
+	}
+
+	if err := r.Delete(context.Background(), updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(healthCheck)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.bodies("DELETE /2013-04-01/healthcheck/HC1")) != 1 {
+		t.Error("health check not deleted")
+	}
+}
+
+func TestReconcileHealthCheckRejectsImmutableChanges(t *testing.T) {
+	fake := newFakeRoute53HealthChecks(t)
+	healthCheck := newTestHealthCheck()
+	healthCheck.Spec.Type = netv1alpha1.HealthCheckTcp
+	healthCheck.Spec.Port = 443
+	healthCheck.Status.HealthCheckId = "HC1"
+	r := newTestHealthCheckReconciler(fake, healthCheck)
+
+	updated := reconcileHealthCheck(t, r, healthCheck)
+	if ready := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionReady); ready == nil || ready.Status != metav1.ConditionFalse || ready.Reason != ReasonInvalidRecord {
+		t.Errorf("expected an InvalidRecord condition, got %+v", ready)
+	}
+	if len(fake.bodies("POST /2013-04-01/healthcheck/HC1")) != 0 {
+		t.Error("the health check must not be updated")
+	}
+}
+
+func TestReconcileResolvesHealthCheckRef(t *testing.T) {
+	memory := newMemoryProvider()
+	record := newTestRecord()
+	record.Spec.Route53Records.SetIdentifier = "primary"
+	record.Spec.Route53Records.Failover = netv1alpha1.FailoverPrimary
+	record.Spec.Route53Records.HealthCheckRef = "www"
+	healthCheck := newTestHealthCheck()
+	r := newTestReconciler(memory, record, healthCheck)
+
+	_, updated := reconcileRecord(t, r, record)
+	if synced := meta.FindStatusCondition(updated.Status.Conditions, netv1alpha1.ConditionSynced); synced == nil || synced.Reason != ReasonReferenceNotReady {
+		t.Errorf("expected a ReferenceNotReady condition, got %+v", synced)
+	}
+	if memory.changes != 0 {
+		t.Error("the record must not be applied before the health check is created")
+	}
+
+	healthCheck.Status.HealthCheckId = "HC1"
+	if err := r.Status().Update(context.Background(), healthCheck); err != nil {
+		t.Fatal(err)
+	}
+	if requests := r.dnsRecordsForHealthCheck(healthCheck); len(requests) != 1 || requests[0].Name != record.Name {
+		t.Errorf("the DnsRecord referencing the health check must be reconciled: %v", requests)
+	}
+	reconcileRecord(t, r, record)
+	applied := memory.records["www.example.com/CNAME/primary"]
+	if applied.Routing.HealthCheckId != "HC1" {
+		t.Errorf("health check id not applied: %+v", applied)
+	}
+}
+
+func TestReconcileDeletesHealthCheckWithoutCredentials(t *testing.T) {
+	fake := newFakeRoute53HealthChecks(t)
+	healthCheck := newTestHealthCheck()
+	healthCheck.Spec.AwsSecrets.SecretName = "missing"
+	healthCheck.Finalizers = []string{healthCheckFinalizer}
+	r := newTestHealthCheckReconciler(fake, healthCheck)
+	key := client.ObjectKeyFromObject(healthCheck)
+
+	// The health check has never been created: the missing Secret does not block the deletion
+	if err := r.Delete(context.Background(), healthCheck); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Get(context.Background(), key, &netv1alpha1.Route53HealthCheck{}); !errors.IsNotFound(err) {
+		t.Errorf("the finalizer must be removed, got %v", err)
+	}
+}
+
+func TestReconcileRetriesHealthCheckDeletion(t *testing.T) {
+	fake := newFakeRoute53HealthChecks(t)
+	healthCheck := newTestHealthCheck()
+	healthCheck.Spec.AwsSecrets.SecretName = "missing"
+	healthCheck.Finalizers = []string{healthCheckFinalizer}
+	healthCheck.Status.HealthCheckId = "HC1"
+	r := newTestHealthCheckReconciler(fake, healthCheck)
+	key := client.ObjectKeyFromObject(healthCheck)
+
+	if err := r.Delete(context.Background(), healthCheck); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err == nil {
+		t.Error("the deletion must be retried until the credentials are available")
+	}
+	if err := r.Get(context.Background(), key, &netv1alpha1.Route53HealthCheck{}); err != nil {
+		t.Errorf("the finalizer must be kept: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 Tommaso Doninelli.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"strings"
+)
+
+// hostnameAnnotation the comma separated hostnames of a LoadBalancer Service, a DnsRecord is created for each
+const hostnameAnnotation = "net.beekube.cloud/hostname"
+
+// ServiceReconciler creates a DnsRecord for each hostname of the annotated LoadBalancer Services,
+// pointing to the address of their load balancer
+type ServiceReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+
+func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	service := &v1.Service{}
+	if err := r.Get(ctx, req.NamespacedName, service); err != nil {
+		if errors.IsNotFound(err) {
+			// The DnsRecords are garbage collected
+			return DoNotRequeue()
+		}
+		return RequeueWithError(err)
+	}
+	if service.GetDeletionTimestamp() != nil {
+		return DoNotRequeue()
+	}
+
+	// The records of a Service that is no longer annotated, or no longer a LoadBalancer, are deleted
+	hostnames := service.Annotations[hostnameAnnotation]
+	if service.Spec.Type != v1.ServiceTypeLoadBalancer || strings.TrimSpace(hostnames) == "" {
+		return RequeueWithError(syncSourceRecords(ctx, r.Client, r.Scheme, service, nil))
+	}
+
+	options, err := sourceOptionsOf(service)
+	if err != nil {
+		logger.Error(err, "invalid annotations, the DnsRecords are not updated")
+		return DoNotRequeue()
+	}
+
+	// The records are left alone until the load balancer has an address
+	addresses := service.Status.LoadBalancer.Ingress
+	if len(addresses) == 0 {
+		logger.Info("waiting for the address of the load balancer")
+		return DoNotRequeue()
+	}
+
+	var hosts []string
+	for _, host := range strings.Split(hostnames, ",") {
+		hosts = append(hosts, strings.TrimSpace(host))
+	}
+	records := sourceRecords("Service", service, hosts, addresses, options)
+	return RequeueWithError(syncSourceRecords(ctx, r.Client, r.Scheme, service, records))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Only the changes to the spec of the owned DnsRecords matter, not their status
+func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Service{}).
+		Owns(&netv1alpha1.DnsRecord{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+)
+
+func newTestService() *v1.Service {
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			UID:         "service-uid",
+			Annotations: map[string]string{hostnameAnnotation: "www.example.com, example.com"},
+		},
+		Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+		Status: v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{
+			Ingress: []v1.LoadBalancerIngress{{Hostname: "lb-1.eu-west-1.elb.amazonaws.com"}},
+		}},
+	}
+}
+
+func TestServiceCreatesRecordsForHostnames(t *testing.T) {
+	service := newTestService()
+	dnsReconciler := newTestReconciler(newMemoryProvider(), service)
+	r := &ServiceReconciler{Client: dnsReconciler.Client, Scheme: dnsReconciler.Scheme}
+
+	records := reconcileSource(t, r, service)
+	if len(records) != 2 {
+		t.Fatalf("expected a record for each hostname, got %v", records)
+	}
+	record := records["web-example.com"]
+	spec := record.Spec.Route53Records
+	if spec.Name != "example.com." || spec.Type != "CNAME" || !reflect.DeepEqual(spec.ResourceRecords, []string{"lb-1.eu-west-1.elb.amazonaws.com"}) {
+		t.Errorf("expected a CNAME to the load balancer, got %+v", spec)
+	}
+	if !metav1.IsControlledBy(&record, service) {
+		t.Errorf("the record must be owned by the Service: %v", record.OwnerReferences)
+	}
+
+	// The load balancer gets an IP
+	service.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "10.0.0.1"}}
+	if err := r.Update(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	records = reconcileSource(t, r, service)
+	if spec := records["web-example.com"].Spec.Route53Records; spec.Type != "A" || !reflect.DeepEqual(spec.ResourceRecords, []string{"10.0.0.1"}) {
+		t.Errorf("expected an A record, got %+v", spec)
+	}
+
+	// A Service no longer a LoadBalancer has no records
+	service.Spec.Type = v1.ServiceTypeClusterIP
+	if err := r.Update(context.Background(), service); err != nil {
+		t.Fatal(err)
+	}
+	if records := reconcileSource(t, r, service); len(records) != 0 {
+		t.Errorf("expected no records, got %v", records)
+	}
+}
+
+func TestServiceCreatesAliasRecords(t *testing.T) {
+	service := newTestService()
+	service.Annotations[hostnameAnnotation] = "example.com"
+	service.Annotations[aliasHostedZoneAnnotation] = "Z32O12XQLNTSW2"
+	service.Annotations[dnsTtlAnnotation] = "60"
+	dnsReconciler := newTestReconciler(newMemoryProvider(), service)
+	r := &ServiceReconciler{Client: dnsReconciler.Client, Scheme: dnsReconciler.Scheme}
+
+	spec := reconcileSource(t, r, service)["web-example.com"].Spec.Route53Records
+	expected := &netv1alpha1.Route53Alias{DNSName: "lb-1.eu-west-1.elb.amazonaws.com", HostedZoneId: "Z32O12XQLNTSW2"}
+	if spec.Type != "A" || spec.Ttl != 0 || len(spec.ResourceRecords) != 0 || !reflect.DeepEqual(spec.Alias, expected) {
+		t.Errorf("expected an alias to the load balancer, got %+v", spec)
+	}
+	if err := spec.Validate(); err != nil {
+		t.Errorf("the alias record must be valid: %v", err)
+	}
+}
+
+func TestServiceWaitsForLoadBalancer(t *testing.T) {
+	service := newTestService()
+	service.Status.LoadBalancer.Ingress = nil
+	dnsReconciler := newTestReconciler(newMemoryProvider(), service)
+	r := &ServiceReconciler{Client: dnsReconciler.Client, Scheme: dnsReconciler.Scheme}
+
+	if records := reconcileSource(t, r, service); len(records) != 0 {
+		t.Errorf("expected no records before the load balancer has an address, got %v", records)
+	}
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"net"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Annotations of the objects the DnsRecords are generated from
+const (
+	// dnsProviderAnnotation the DnsProvider of the generated records, or ClusterDnsProvider/<name>.
+	// Without it the records use the default AWS credential chain of the operator, if --allow-default-aws-credentials is set
+	dnsProviderAnnotation = "net.beekube.cloud/dns-provider"
+	// dnsTtlAnnotation the ttl of the generated records, in seconds
+	dnsTtlAnnotation = "net.beekube.cloud/dns-ttl"
+	// aliasHostedZoneAnnotation the canonical hosted zone of the load balancer: when set, the hosts get
+	// a Route53 alias to the hostname of the load balancer instead of a CNAME
+	aliasHostedZoneAnnotation = "net.beekube.cloud/alias-hosted-zone-id"
+)
+
+// sourceUidLabel the uid of the object a DnsRecord is generated from
+const sourceUidLabel = "net.beekube.cloud/source-uid"
+
+// sourceOptions the settings of the generated records, read from the annotations of their source
+type sourceOptions struct {
+	ProviderRef       *netv1alpha1.DnsProviderRef
+	Ttl               int64
+	AliasHostedZoneId string
+}
+
+// sourceOptionsOf returns the options set by the annotations of the source
+func sourceOptionsOf(source client.Object) (sourceOptions, error) {
+	var options sourceOptions
+	annotations := source.GetAnnotations()
+	if provider := annotations[dnsProviderAnnotation]; provider != "" {
+		ref := netv1alpha1.DnsProviderRef{Name: provider, Kind: netv1alpha1.DnsProviderKind}
+		if parts := strings.SplitN(provider, "/", 2); len(parts) == 2 {
+			if parts[0] != netv1alpha1.DnsProviderKind && parts[0] != netv1alpha1.ClusterDnsProviderKind {
+				return options, fmt.Errorf("invalid %s %q: the kind must be DnsProvider or ClusterDnsProvider", dnsProviderAnnotation, provider)
+			}
+			ref = netv1alpha1.DnsProviderRef{Name: parts[1], Kind: parts[0]}
+		}
+		options.ProviderRef = &ref
+	}
+	if ttl := annotations[dnsTtlAnnotation]; ttl != "" {
+		seconds, err := strconv.ParseInt(ttl, 10, 64)
+		if err != nil || seconds < 1 {
+			return options, fmt.Errorf("invalid %s %q: must be a positive number of seconds", dnsTtlAnnotation, ttl)
+		}
+		options.Ttl = seconds
+	}
+	options.AliasHostedZoneId = annotations[aliasHostedZoneAnnotation]
+	return options, nil
+}
+
+// sourceRecords returns the DnsRecords pointing the hosts to the load balancer of the source, an object of the given kind.
+// Each host gets an A record for the IPv4 addresses, or a CNAME (or an alias) to the first hostname, and an AAAA record
+// for the IPv6 addresses. A CNAME can't share its name with other records: the IPv6 addresses are left to it
+func sourceRecords(kind string, source client.Object, hosts []string, addresses []v1.LoadBalancerIngress, options sourceOptions) []netv1alpha1.DnsRecord {
+	var ipv4, ipv6 []string
+	var hostname string
+	for _, address := range addresses {
+		if ip := net.ParseIP(address.IP); ip != nil {
+			if ip.To4() != nil {
+				ipv4 = append(ipv4, address.IP)
+			} else {
+				ipv6 = append(ipv6, address.IP)
+			}
+		} else if address.Hostname != "" && hostname == "" {
+			hostname = address.Hostname
+		}
+	}
+	sort.Strings(ipv4)
+	sort.Strings(ipv6)
+
+	var records []netv1alpha1.DnsRecord
+	seen := map[string]bool{}
+	for _, host := range hosts {
+		host = normalizeDnsName(host)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+
+		name := sourceRecordName(source.GetName(), host)
+		if len(ipv4) > 0 {
+			records = append(records, sourceRecord(kind, source, name, host, "A", ipv4, options))
+		} else if hostname != "" && options.AliasHostedZoneId != "" {
+			record := sourceRecord(kind, source, name, host, "A", nil, options)
+			record.Spec.Route53Records.Ttl = 0
+			record.Spec.Route53Records.Alias = &netv1alpha1.Route53Alias{DNSName: hostname, HostedZoneId: options.AliasHostedZoneId}
+			records = append(records, record)
+		} else if hostname != "" {
+			records = append(records, sourceRecord(kind, source, name, host, "CNAME", []string{hostname}, options))
+			continue
+		}
+		if len(ipv6) > 0 {
+			records = append(records, sourceRecord(kind, source, name+"-aaaa", host, "AAAA", ipv6, options))
+		}
+	}
+	return records
+}
+
+func sourceRecord(kind string, source client.Object, name, host, recordType string, values []string, options sourceOptions) netv1alpha1.DnsRecord {
+	return netv1alpha1.DnsRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: source.GetNamespace(),
+			Labels:    map[string]string{sourceUidLabel: string(source.GetUID())},
+		},
+		Spec: netv1alpha1.DnsRecordSpec{
+			ProviderRef: options.ProviderRef,
+			Route53Records: netv1alpha1.Route53Record{
+				Name:            host + ".",
+				Type:            recordType,
+				Ttl:             options.Ttl,
+				ResourceRecords: values,
+				Comment:         kind + " " + source.GetNamespace() + "/" + source.GetName(),
+			},
+		},
+	}
+}
+
+// sourceRecordName the name of the DnsRecord of a host, like www-example.com for the host example.com of
+// the Ingress www. Names too long or invalid are replaced by a hash of the host
+func sourceRecordName(sourceName, host string) string {
+	name := sourceName + "-" + strings.Replace(host, "*", "wildcard", 1)
+	// Room for the -aaaa suffix
+	if len(name) <= validation.DNS1123SubdomainMaxLength-5 && len(validation.IsDNS1123Subdomain(name)) == 0 {
+		return name
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(host)))[:10]
+	if max := validation.DNS1123SubdomainMaxLength - 5 - len(hash) - 1; len(sourceName) > max {
+		sourceName = sourceName[:max]
+	}
+	return sourceName + "-" + hash
+}
+
+// syncSourceRecords creates or updates the desired DnsRecords, controlled by the source, and deletes
+// the records of the source that are no longer desired. The DnsRecords are garbage collected with the source
+func syncSourceRecords(ctx context.Context, c client.Client, scheme *runtime.Scheme, source client.Object, desired []netv1alpha1.DnsRecord) error {
+	names := map[string]bool{}
+	for i := range desired {
+		record := &desired[i]
+		names[record.Name] = true
+		if err := defaultSourceRecord(ctx, c, record); err != nil {
+			return err
+		}
+
+		existing := &netv1alpha1.DnsRecord{}
+		err := c.Get(ctx, client.ObjectKeyFromObject(record), existing)
+		if apierrors.IsNotFound(err) {
+			if err := controllerutil.SetControllerReference(source, record, scheme); err != nil {
+				return err
+			}
+			if err := c.Create(ctx, record); err != nil {
+				return fmt.Errorf("can't create the DnsRecord %s: %w", record.Name, err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !metav1.IsControlledBy(existing, source) {
+			return fmt.Errorf("the DnsRecord %s already exists and is not managed by %s", record.Name, source.GetName())
+		}
+		updated := existing.DeepCopy()
+		if updated.Labels == nil {
+			updated.Labels = map[string]string{}
+		}
+		updated.Labels[sourceUidLabel] = record.Labels[sourceUidLabel]
+		updated.Spec = record.Spec
+		if !reflect.DeepEqual(existing, updated) {
+			if err := c.Update(ctx, updated); err != nil {
+				return fmt.Errorf("can't update the DnsRecord %s: %w", record.Name, err)
+			}
+		}
+	}
+
+	owned := &netv1alpha1.DnsRecordList{}
+	if err := c.List(ctx, owned, client.InNamespace(source.GetNamespace()), client.MatchingLabels{sourceUidLabel: string(source.GetUID())}); err != nil {
+		return err
+	}
+	for i := range owned.Items {
+		record := &owned.Items[i]
+		if names[record.Name] || !metav1.IsControlledBy(record, source) {
+			continue
+		}
+		if err := c.Delete(ctx, record); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("can't delete the DnsRecord %s: %w", record.Name, err)
+		}
+	}
+	return nil
+}
+
+// defaultSourceRecord applies the defaults of the webhook and of the CRD to a generated record,
+// so that it is compared with the stored one, already defaulted, without updating it every time
+func defaultSourceRecord(ctx context.Context, c client.Client, record *netv1alpha1.DnsRecord) error {
+	if err := (&DnsRecordWebhook{Client: c}).Default(ctx, record); err != nil {
+		return err
+	}
+	if record.Spec.DriftPolicy == "" {
+		record.Spec.DriftPolicy = netv1alpha1.DriftPolicyCorrect
+	}
+	if record.Spec.AdoptPolicy == "" {
+		record.Spec.AdoptPolicy = netv1alpha1.AdoptPolicyNever
+	}
+	return nil
+}
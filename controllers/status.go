@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Values of DnsRecordStatus.Status
+const (
+	StatusSynced   = "Synced"
+	StatusError    = "Error"
+	StatusDeleting = "Deleting"
+)
+
+// Reasons used in the DnsRecord conditions
+const (
+	ReasonApplied        = "Applied"
+	ReasonProviderError  = "ProviderError"
+	ReasonNoProvider     = "NoProvider"
+	ReasonChangePending  = "ChangePending"
+	ReasonChangeInSync   = "ChangeInSync"
+	ReasonDeleting       = "Deleting"
+	ReasonInSync         = "InSync"
+	ReasonDriftDetected  = "DriftDetected"
+	ReasonDriftCorrected = "DriftCorrected"
+)
+
+// setCondition adds or updates a condition, tagging it with the current generation of the record
+func setCondition(crd *netv1alpha1.DnsRecord, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&crd.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: crd.Generation,
+	})
+}
+
+// setSynced records a successful call to the dns provider
+func setSynced(crd *netv1alpha1.DnsRecord, changeId string) {
+	now := metav1.Now()
+	crd.Status.Status = StatusSynced
+	crd.Status.ChangeId = changeId
+	crd.Status.LastSyncTime = &now
+	crd.Status.ObservedGeneration = crd.Generation
+	crd.Status.AppliedHash = specHash(crd)
+	desired := desiredRecordSet(crd)
+	crd.Status.AppliedRecord = &netv1alpha1.AppliedRecord{
+		ZoneId:          crd.Spec.Route53Records.ZoneId,
+		Name:            desired.Name,
+		Type:            desired.Type,
+		Ttl:             desired.Ttl,
+		ResourceRecords: desired.Values,
+		Alias:           desired.Alias,
+		Route53Routing:  desired.Routing,
+	}
+	crd.Status.Failures = 0
+	setCondition(crd, netv1alpha1.ConditionSynced, metav1.ConditionTrue, ReasonApplied, "Record applied to the dns provider")
+	setCondition(crd, netv1alpha1.ConditionReady, metav1.ConditionTrue, ReasonApplied, "Record applied to the dns provider")
+	if changeId != "" {
+		setCondition(crd, netv1alpha1.ConditionPropagated, metav1.ConditionFalse, ReasonChangePending, "Waiting for change "+changeId+" to propagate")
+	} else {
+		setCondition(crd, netv1alpha1.ConditionPropagated, metav1.ConditionTrue, ReasonApplied, "The dns provider does not track changes")
+	}
+}
+
+// isChangePending returns true if the change submitted for the current spec has not propagated yet
+func isChangePending(crd *netv1alpha1.DnsRecord) bool {
+	propagated := meta.FindStatusCondition(crd.Status.Conditions, netv1alpha1.ConditionPropagated)
+	return crd.Status.ChangeId != "" &&
+		isApplied(crd) &&
+		propagated != nil &&
+		propagated.Reason == ReasonChangePending
+}
+
+// isApplied returns true if the current spec has been applied successfully.
+// Changes to the spec that don't affect the dns (like the drift policy) don't require a new apply
+func isApplied(crd *netv1alpha1.DnsRecord) bool {
+	return crd.Status.AppliedHash == specHash(crd) &&
+		meta.IsStatusConditionTrue(crd.Status.Conditions, netv1alpha1.ConditionSynced)
+}
+
+// specHash returns a hash of the part of the spec sent to the dns provider
+func specHash(crd *netv1alpha1.DnsRecord) string {
+	data, _ := json.Marshal(crd.Spec.Route53Records)
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+}
+
+// setFailed records a failure, the reason is a CamelCase code describing it
+func setFailed(crd *netv1alpha1.DnsRecord, reason, message string) {
+	crd.Status.Status = StatusError
+	crd.Status.ObservedGeneration = crd.Generation
+	setCondition(crd, netv1alpha1.ConditionSynced, metav1.ConditionFalse, reason, message)
+	setCondition(crd, netv1alpha1.ConditionReady, metav1.ConditionFalse, reason, message)
+}
+
+// updateStatus persists the status subresource. Errors are logged and returned
+func (r *DnsRecordReconciler) updateStatus(ctx context.Context, crd *netv1alpha1.DnsRecord) error {
+	if err := r.Status().Update(ctx, crd); err != nil {
+		log.FromContext(ctx).Error(err, "can't update the DnsRecord status")
+		return err
+	}
+	return nil
+}
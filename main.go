@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -32,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	netv1alpha1 "github.com/totomz/kube-dns-operator/api/v1alpha1"
+	netv1beta1 "github.com/totomz/kube-dns-operator/api/v1beta1"
 	"github.com/totomz/kube-dns-operator/controllers"
 	// +kubebuilder:scaffold:imports
 )
@@ -45,6 +47,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(netv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(netv1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -52,11 +55,23 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var awsRegion string
+	var awsEndpoint string
+	var resyncPeriod time.Duration
+	var ownerId string
+	var allowDefaultAwsCredentials bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&awsRegion, "aws-region", controllers.DefaultAwsRegion, "Default AWS region used for the Route53 API calls.")
+	flag.StringVar(&awsEndpoint, "aws-endpoint", "", "Default Route53 endpoint URL. Leave empty to use the AWS public endpoint.")
+	flag.DurationVar(&resyncPeriod, "resync-period", 10*time.Minute, "How often the live dns records are compared with the DnsRecords. 0 disables the resync.")
+	flag.StringVar(&ownerId, "owner-id", controllers.DefaultOwnerId, "Identifies this operator in the ownership TXT records. Must be unique among the clusters sharing a hosted zone.")
+	flag.BoolVar(&allowDefaultAwsCredentials, "allow-default-aws-credentials", false,
+		"Let the resources without awsSecrets use the default AWS credential chain of the operator (IRSA, instance role, env). "+
+			"The ClusterDnsProviders can always use it.")
 	opts := zap.Options{
 		Development: false,
 	}
@@ -78,13 +93,51 @@ func main() {
 		os.Exit(1)
 	}
 
+	route53Provider := controllers.NewRoute53Provider(mgr.GetClient())
+	route53Provider.Region = awsRegion
+	route53Provider.Endpoint = awsEndpoint
+	route53Provider.OwnerId = ownerId
+	route53Provider.AllowDefaultCredentials = allowDefaultAwsCredentials
+
 	if err = (&controllers.DnsRecordReconciler{
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		Providers:    controllers.NewProviderRegistry(route53Provider),
+		ResyncPeriod: resyncPeriod,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DnsRecord")
+		os.Exit(1)
+	}
+	if err = (&controllers.Route53HealthCheckReconciler{
+		Client:  mgr.GetClient(),
+		Scheme:  mgr.GetScheme(),
+		Route53: route53Provider,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Route53HealthCheck")
+		os.Exit(1)
+	}
+	if err = (&controllers.IngressReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "DnsRecord")
+		setupLog.Error(err, "unable to create controller", "controller", "Ingress")
 		os.Exit(1)
 	}
+	if err = (&controllers.ServiceReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Service")
+		os.Exit(1)
+	}
+	// Set ENABLE_WEBHOOKS=false to run the manager locally, without the webhook certificates.
+	// The webhook server converts the v1beta1 DnsRecords too
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&controllers.DnsRecordWebhook{Client: mgr.GetClient(), Route53: route53Provider}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DnsRecord")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {